@@ -2,16 +2,60 @@ package v1
 
 import (
 	"LANShare/model"
+	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-type BluetoothService struct{}
+// bluetoothScanEventName 是 ScanStream 的设备事件在 Wails 事件总线上使用的名字，
+// 前端通过 EventsOn("bluetooth:device", ...) 订阅。
+const bluetoothScanEventName = "bluetooth:device"
+
+// bluetoothSessionEventName 是已建立连接会话的 notify/disconnect 事件在 Wails
+// 事件总线上使用的名字，前端通过 EventsOn("bluetooth:session", ...) 订阅。
+const bluetoothSessionEventName = "bluetooth:session"
+
+// BluetoothService 是暴露给 Wails 前端的蓝牙 API。内部持有一个单例的
+// model.BluetoothService，使得 Connect 之后建立的会话能在后续调用中被复用。
+type BluetoothService struct {
+	svc               *model.BluetoothService
+	ctx               context.Context
+	sessionEventsOnce sync.Once
+}
+
+func (g *BluetoothService) backend() *model.BluetoothService {
+	if g.svc == nil {
+		g.svc = model.NewBluetoothService()
+	}
+	return g.svc
+}
+
+// SetContext 由 App.OnStartup 调用，保存 Wails 运行时上下文以便后续推送事件，
+// 并启动 GATT 连接会话的 notify/disconnect 事件桥接。
+func (g *BluetoothService) SetContext(ctx context.Context) {
+	g.ctx = ctx
+	g.startSessionEvents()
+}
+
+// startSessionEvents 把 backend().Events() 上的 notify/disconnect 事件转发到
+// Wails 事件总线（"bluetooth:session"），只需要启动一次。
+func (g *BluetoothService) startSessionEvents() {
+	g.sessionEventsOnce.Do(func() {
+		go func() {
+			for ev := range g.backend().Events() {
+				runtime.EventsEmit(g.ctx, bluetoothSessionEventName, ev)
+			}
+		}()
+	})
+}
 
 // BluetoothScanApi 扫描指定秒数并返回设备列表的 JSON 字符串。
 func (g *BluetoothService) BluetoothScanApi(seconds int) (string, error) {
-	svc := &model.BluetoothService{}
-	devs, err := svc.Scan(seconds)
+	devs, err := g.backend().Scan(seconds)
 	if err != nil {
 		fmt.Println(err)
 		return "", err
@@ -24,3 +68,66 @@ func (g *BluetoothService) BluetoothScanApi(seconds int) (string, error) {
 	fmt.Println(string(b))
 	return string(b), nil
 }
+
+// BluetoothConnectApi 连接到指定地址的蓝牙外设，timeoutSeconds<=0 时使用默认超时。
+// 返回值是用于后续 Discover/Subscribe/Write/Disconnect 调用的会话 ID。
+func (g *BluetoothService) BluetoothConnectApi(addr string, timeoutSeconds int) (string, error) {
+	sid, err := g.backend().Connect(addr, time.Duration(timeoutSeconds)*time.Second)
+	if err != nil {
+		fmt.Println(err)
+		return "", err
+	}
+	return string(sid), nil
+}
+
+// BluetoothDiscoverServicesApi 枚举某个会话下外设的 GATT 服务，返回 JSON 字符串。
+func (g *BluetoothService) BluetoothDiscoverServicesApi(sessionID string) (string, error) {
+	svcs, err := g.backend().DiscoverServices(model.SessionID(sessionID))
+	if err != nil {
+		fmt.Println(err)
+		return "", err
+	}
+	b, err := json.Marshal(svcs)
+	if err != nil {
+		fmt.Println(err)
+		return "", err
+	}
+	return string(b), nil
+}
+
+// BluetoothSubscribeApi 订阅某个特征的通知，数据通过会话的 Events() 通道广播。
+func (g *BluetoothService) BluetoothSubscribeApi(sessionID, charUUID string) error {
+	return g.backend().Subscribe(model.SessionID(sessionID), charUUID)
+}
+
+// BluetoothWriteApi 向某个特征写入数据。
+func (g *BluetoothService) BluetoothWriteApi(sessionID, charUUID string, data []byte) error {
+	return g.backend().Write(model.SessionID(sessionID), charUUID, data)
+}
+
+// BluetoothDisconnectApi 断开某个会话。
+func (g *BluetoothService) BluetoothDisconnectApi(sessionID string) error {
+	return g.backend().Disconnect(model.SessionID(sessionID))
+}
+
+// BluetoothScanStreamStartApi 开始一次持续扫描，设备的 Added/Updated/Lost 事件
+// 通过 Wails 事件总线（"bluetooth:device"）实时推送给前端，取代一次性的
+// BluetoothScanApi 轮询。
+func (g *BluetoothService) BluetoothScanStreamStartApi() error {
+	events, err := g.backend().ScanStream(g.ctx)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	go func() {
+		for ev := range events {
+			runtime.EventsEmit(g.ctx, bluetoothScanEventName, ev)
+		}
+	}()
+	return nil
+}
+
+// BluetoothScanStreamStopApi 结束 BluetoothScanStreamStartApi 发起的扫描。
+func (g *BluetoothService) BluetoothScanStreamStopApi() {
+	g.backend().StopScan()
+}
@@ -0,0 +1,234 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenScope 描述一个令牌被允许执行的操作范围。
+type TokenScope string
+
+const (
+	ScopeReadOnly  TokenScope = "ro"
+	ScopeReadWrite TokenScope = "rw"
+)
+
+// writeMethods 是 WebDAV 协议里会修改内容的方法，只读令牌不允许使用。
+var writeMethods = map[string]bool{
+	http.MethodPut:    true,
+	http.MethodDelete: true,
+	http.MethodPatch:  true,
+	"MKCOL":           true,
+	"COPY":            true,
+	"MOVE":            true,
+	"PROPPATCH":       true,
+	"LOCK":            true,
+	"UNLOCK":          true,
+}
+
+// Token 是颁发给某个对端的短期凭证，限定了作用域（只读/读写）与路径前缀。
+type Token struct {
+	ID         string     `json:"id"`
+	PeerID     string     `json:"peerId"`
+	Scope      TokenScope `json:"scope"`
+	PathPrefix string     `json:"pathPrefix"` // 空字符串表示整个共享根目录
+	ExpiresAt  time.Time  `json:"expiresAt"`
+}
+
+// Expired 判断令牌是否已过期。
+func (t Token) Expired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// Allows 判断该令牌是否允许对给定路径执行给定 HTTP 方法。
+func (t Token) Allows(method, path string) bool {
+	if t.PathPrefix != "" && path != t.PathPrefix && !strings.HasPrefix(path, t.PathPrefix+"/") {
+		return false
+	}
+	if t.Scope == ScopeReadOnly && writeMethods[method] {
+		return false
+	}
+	return true
+}
+
+// TokenStore 是令牌的持久化接口，提供内存与文件两种实现。
+type TokenStore interface {
+	// Issue 颁发一个新令牌并返回该令牌（其 ID 即 Bearer 凭证）。
+	Issue(peerID string, scope TokenScope, pathPrefix string, ttl time.Duration) (Token, error)
+	// Revoke 撤销一个令牌；撤销一个不存在的令牌不是错误。
+	Revoke(id string) error
+	// Lookup 返回某个令牌 ID 对应的 Token；过期的令牌会被当场清除并视为不存在。
+	Lookup(id string) (Token, bool)
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// MemTokenStore 是令牌的纯内存实现，进程重启后所有令牌失效。
+type MemTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+}
+
+// NewMemTokenStore 创建一个空的内存令牌存储。
+func NewMemTokenStore() *MemTokenStore {
+	return &MemTokenStore{tokens: make(map[string]Token)}
+}
+
+func (s *MemTokenStore) Issue(peerID string, scope TokenScope, pathPrefix string, ttl time.Duration) (Token, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return Token{}, err
+	}
+	tok := Token{ID: id, PeerID: peerID, Scope: scope, PathPrefix: pathPrefix, ExpiresAt: time.Now().Add(ttl)}
+	s.mu.Lock()
+	s.tokens[id] = tok
+	s.mu.Unlock()
+	return tok, nil
+}
+
+func (s *MemTokenStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, id)
+	return nil
+}
+
+func (s *MemTokenStore) Lookup(id string) (Token, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tok, ok := s.tokens[id]
+	if !ok {
+		return Token{}, false
+	}
+	if tok.Expired() {
+		delete(s.tokens, id)
+		return Token{}, false
+	}
+	return tok, true
+}
+
+// FileTokenStore 在 MemTokenStore 的基础上把令牌表落盘到一个 JSON 文件，
+// 每次 Issue/Revoke 后重写整个文件，这样进程重启后已颁发的令牌仍然有效。
+type FileTokenStore struct {
+	mem  *MemTokenStore
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileTokenStore 创建一个持久化到 path 的令牌存储，若文件已存在会先加载其内容。
+func NewFileTokenStore(path string) (*FileTokenStore, error) {
+	s := &FileTokenStore{mem: NewMemTokenStore(), path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileTokenStore) load() error {
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read token store %s: %w", s.path, err)
+	}
+	var tokens map[string]Token
+	if err := json.Unmarshal(b, &tokens); err != nil {
+		return fmt.Errorf("parse token store %s: %w", s.path, err)
+	}
+	s.mem.mu.Lock()
+	s.mem.tokens = tokens
+	s.mem.mu.Unlock()
+	return nil
+}
+
+func (s *FileTokenStore) persist() error {
+	s.mem.mu.Lock()
+	b, err := json.MarshalIndent(s.mem.tokens, "", "  ")
+	s.mem.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal token store: %w", err)
+	}
+	if err := os.WriteFile(s.path, b, 0600); err != nil {
+		return fmt.Errorf("write token store %s: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *FileTokenStore) Issue(peerID string, scope TokenScope, pathPrefix string, ttl time.Duration) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tok, err := s.mem.Issue(peerID, scope, pathPrefix, ttl)
+	if err != nil {
+		return Token{}, err
+	}
+	if err := s.persist(); err != nil {
+		return Token{}, err
+	}
+	return tok, nil
+}
+
+func (s *FileTokenStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.mem.Revoke(id); err != nil {
+		return err
+	}
+	return s.persist()
+}
+
+func (s *FileTokenStore) Lookup(id string) (Token, bool) {
+	return s.mem.Lookup(id)
+}
+
+// constantTimeEqual 以恒定时间比较两个字符串，避免 HTTP Basic 凭证校验
+// 通过响应耗时泄露匹配了多少个前缀字符。
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// authMiddleware 包装一个 WebDAV handler，要求每个请求携带 HTTP Basic 凭证
+// （用于受信任的单用户场景）或者一个 Bearer 令牌（由 TokenStore 颁发，按
+// 作用域与路径前缀限制权限）。
+func authMiddleware(next http.Handler, store TokenStore, basicUser, basicPass string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if basicUser != "" {
+			if user, pass, ok := r.BasicAuth(); ok && constantTimeEqual(user, basicUser) && constantTimeEqual(pass, basicPass) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if authz := r.Header.Get("Authorization"); strings.HasPrefix(authz, "Bearer ") {
+			tokenID := strings.TrimPrefix(authz, "Bearer ")
+			tok, ok := store.Lookup(tokenID)
+			if !ok {
+				http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+			if !tok.Allows(r.Method, r.URL.Path) {
+				http.Error(w, "token does not permit this operation", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="lanshare"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTokenAllowsScope(t *testing.T) {
+	ro := Token{Scope: ScopeReadOnly, ExpiresAt: time.Now().Add(time.Hour)}
+	if !ro.Allows(http.MethodGet, "/foo") {
+		t.Fatalf("read-only token should allow GET")
+	}
+	if ro.Allows(http.MethodPut, "/foo") {
+		t.Fatalf("read-only token must not allow PUT")
+	}
+	if ro.Allows("MKCOL", "/foo") {
+		t.Fatalf("read-only token must not allow MKCOL")
+	}
+
+	rw := Token{Scope: ScopeReadWrite, ExpiresAt: time.Now().Add(time.Hour)}
+	if !rw.Allows(http.MethodPut, "/foo") {
+		t.Fatalf("read-write token should allow PUT")
+	}
+}
+
+func TestTokenAllowsPathPrefix(t *testing.T) {
+	tok := Token{Scope: ScopeReadWrite, PathPrefix: "/shared/photos", ExpiresAt: time.Now().Add(time.Hour)}
+	if !tok.Allows(http.MethodGet, "/shared/photos/a.jpg") {
+		t.Fatalf("token should allow paths under its prefix")
+	}
+	if tok.Allows(http.MethodGet, "/shared/docs/a.txt") {
+		t.Fatalf("token must not allow paths outside its prefix")
+	}
+	if tok.Allows(http.MethodGet, "/shared/photos-leaked/a.jpg") {
+		t.Fatalf("token scoped to /shared/photos must not match the sibling /shared/photos-leaked")
+	}
+	if tok.Allows(http.MethodGet, "/shared/photosecret") {
+		t.Fatalf("token scoped to /shared/photos must not match /shared/photosecret")
+	}
+	if !tok.Allows(http.MethodGet, "/shared/photos") {
+		t.Fatalf("token should allow an exact match of its own prefix")
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	tok := Token{ExpiresAt: time.Now().Add(-time.Minute)}
+	if !tok.Expired() {
+		t.Fatalf("token with past ExpiresAt should be expired")
+	}
+}
+
+func TestMemTokenStoreLookupClearsExpired(t *testing.T) {
+	s := NewMemTokenStore()
+	tok, err := s.Issue("peer-1", ScopeReadOnly, "", -time.Minute)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if _, ok := s.Lookup(tok.ID); ok {
+		t.Fatalf("expired token should not be returned by Lookup")
+	}
+	if _, ok := s.Lookup(tok.ID); ok {
+		t.Fatalf("expired token should have been evicted by the first Lookup")
+	}
+}
+
+func TestMemTokenStoreRevoke(t *testing.T) {
+	s := NewMemTokenStore()
+	tok, err := s.Issue("peer-1", ScopeReadWrite, "", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if err := s.Revoke(tok.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, ok := s.Lookup(tok.ID); ok {
+		t.Fatalf("revoked token should no longer be found")
+	}
+	if err := s.Revoke("does-not-exist"); err != nil {
+		t.Fatalf("revoking an unknown token should not error: %v", err)
+	}
+}
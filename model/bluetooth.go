@@ -2,90 +2,76 @@ package model
 
 import (
 	"context"
-	"errors"
-	"log"
 	"sync"
 	"time"
 
 	"github.com/go-ble/ble"
-	"github.com/go-ble/ble/examples/lib/dev"
 )
 
 // Device 表示一个被扫描到的蓝牙设备
 type Device struct {
-	Addr string `json:"addr"`
-	Name string `json:"name"`
-	RSSI int    `json:"rssi"`
+	Addr     string        `json:"addr"`
+	Name     string        `json:"name"`
+	RSSI     int           `json:"rssi"`
+	LANShare *LANShareInfo `json:"lanShare,omitempty"` // 仅当广播中携带 LANShare manufacturer-data 时非空
 }
 
-// BluetoothService 提供蓝牙扫描功能（平台实现放在 platform-specific 文件中）
-type BluetoothService struct{}
+// BluetoothService 提供蓝牙扫描与连接功能（扫描见 bluetooth_scan_stream.go，
+// 连接会话见 bluetooth_session.go）。进行中的 ScanStream 扫描记录在实例上，
+// 只能由同一实例的 StopScan 结束；建立的连接会话保存在内嵌的 ConnManager 中，
+// 因此调用方应当复用同一个 BluetoothService 实例，而不是每次请求都新建。
+type BluetoothService struct {
+	mu         sync.Mutex
+	conns      *ConnManager
+	scanCancel context.CancelFunc
+}
 
-func scanImpl(seconds int) ([]Device, error) {
-	if seconds <= 0 {
-		log.Printf("Scan called with non-positive seconds=%d, using default 5s", seconds)
-		seconds = 5
-	}
+// NewBluetoothService 创建一个可用于建立连接会话的 BluetoothService。
+func NewBluetoothService() *BluetoothService {
+	return &BluetoothService{conns: NewConnManager()}
+}
 
-	log.Printf("creating BLE device (platform-specific)...")
-	d, err := dev.NewDevice("default")
-	if err != nil {
-		log.Printf("failed to create device: %v", err)
-		return nil, err
+// conn 惰性初始化连接管理器，兼容直接用 &BluetoothService{} 构造的旧用法。
+func (b *BluetoothService) conn() *ConnManager {
+	if b.conns == nil {
+		b.conns = NewConnManager()
 	}
-	ble.SetDefaultDevice(d)
+	return b.conns
+}
 
-	log.Printf("starting scan for %d seconds", seconds)
-	ctx := ble.WithSigHandler(context.WithTimeout(context.Background(), time.Duration(seconds)*time.Second))
+// Connect 连接到指定地址的蓝牙外设，返回可用于后续 GATT 操作的会话 ID。
+func (b *BluetoothService) Connect(addr string, timeout time.Duration) (SessionID, error) {
+	return b.conn().Connect(addr, timeout)
+}
 
-	var devicesMu sync.Mutex
-	devices := make(map[string]Device)
+// DiscoverServices 枚举某个会话下外设暴露的 GATT 服务与特征。
+func (b *BluetoothService) DiscoverServices(sid SessionID) ([]*ble.Service, error) {
+	return b.conn().DiscoverServices(sid)
+}
 
-	// 发现回调
-	advHandler := func(a ble.Advertisement) {
-		addr := a.Addr().String()
-		displayName := a.LocalName()
-		if displayName == "" {
-			return
-		}
-		// log each advertisement so we can see whether the callback fires
-		log.Printf("adv received: addr=%s name=%q rssi=%d svcCount=%d", addr, a.LocalName(), a.RSSI(), len(a.Services()))
-		devicesMu.Lock()
-		defer devicesMu.Unlock()
-		dvc := Device{Addr: addr, Name: displayName, RSSI: a.RSSI()}
-		devices[addr] = dvc
-	}
+// Subscribe 订阅某个特征的通知，数据通过 Events() 广播。
+func (b *BluetoothService) Subscribe(sid SessionID, charUUID string) error {
+	return b.conn().Subscribe(sid, charUUID)
+}
 
-	// 开始扫描
-	if err := ble.Scan(ctx, true, func(a ble.Advertisement) {
-		advHandler(a)
-	}, nil); err != nil {
-		// Treat context deadline exceeded or cancellation as normal completion
-		// so we can still collect and return any found devices. Only return
-		// non-context errors as failures.
-		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-			log.Printf("scan finished by timeout/cancel: %v", err)
-		} else {
-			log.Printf("scan finished with error: %v", err)
-			return nil, err
-		}
-	}
+// Write 向某个特征写入数据。
+func (b *BluetoothService) Write(sid SessionID, charUUID string, data []byte) error {
+	return b.conn().Write(sid, charUUID, data)
+}
 
-	log.Printf("scan finished, collecting results...")
+// Disconnect 断开某个会话。
+func (b *BluetoothService) Disconnect(sid SessionID) error {
+	return b.conn().Disconnect(sid)
+}
 
-	// 从 map 收集结果
-	devicesMu.Lock()
-	defer devicesMu.Unlock()
-	res := make([]Device, 0, len(devices))
-	for _, v := range devices {
-		res = append(res, v)
-	}
-	log.Printf("found %d unique devices", len(res))
-	return res, nil
+// Events 返回连接会话的 notify/disconnect 事件通道，供 Wails 前端订阅。
+func (b *BluetoothService) Events() <-chan SessionEvent {
+	return b.conn().Events()
 }
 
 // Scan 在给定的秒数内扫描附近的蓝牙设备并返回设备列表。
-// 如果平台不支持，会返回 error。
+// 如果平台不支持，会返回 error。它是 ScanStream 的一个薄封装：订阅事件流
+// 直到超时，再把 Added/Updated/Lost 事件折叠成一份最终快照，仅为向后兼容保留。
 func (b *BluetoothService) Scan(seconds int) ([]Device, error) {
-	return scanImpl(seconds)
+	return b.ScanBatch(seconds)
 }
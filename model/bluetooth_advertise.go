@@ -0,0 +1,236 @@
+package model
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-ble/ble"
+)
+
+// lanshareManufacturerID 是塞进 BLE 广播 manufacturer-data 里的自定义 16 位厂商 ID，
+// 用来让扫描方无需连接即可识别出一条广播是否来自 LANShare 节点。
+const lanshareManufacturerID uint16 = 0x4c53 // "LS"
+
+// lanshareAdvTTL 是一条被扫描到的 LANShare 广播在缓存中保留的最长时间，超过后视为过期。
+const lanshareAdvTTL = 5 * time.Minute
+
+// LANShareInfo 是从 manufacturer-data 中解出的、属于 LANShare 节点的元数据。
+// 它被附加到 Scan/ScanStream 返回的 Device 上，调用方无需连接即可判断对方
+// 提供的服务集合自上次见到以来是否发生变化。
+type LANShareInfo struct {
+	Fingerprint [8]byte   `json:"fingerprint"` // FNV hash of {hostname, port, root fingerprint}
+	IP          net.IP    `json:"ip"`
+	Port        uint16    `json:"port"`
+	LastSeen    time.Time `json:"lastSeen"`
+}
+
+// Changed 判断与上一次见到的广播相比，节点暴露的服务集合（通过指纹近似）是否变化。
+func (i LANShareInfo) Changed(prev LANShareInfo) bool {
+	return i.Fingerprint != prev.Fingerprint
+}
+
+// encodeManufacturerData 按 {8 字节指纹}{4 字节 IPv4}{2 字节端口} 的布局编码
+// manufacturer-data 载荷（不含厂商 ID，ble 库会单独处理）。
+func encodeManufacturerData(hostname string, port int, rootFingerprint string, ip net.IP) []byte {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(hostname))
+	_, _ = h.Write([]byte(fmt.Sprintf(":%d:", port)))
+	_, _ = h.Write([]byte(rootFingerprint))
+	sum := h.Sum64()
+
+	buf := make([]byte, 14)
+	binary.BigEndian.PutUint64(buf[0:8], sum)
+	ip4 := ip.To4()
+	if ip4 == nil {
+		ip4 = net.IPv4zero.To4()
+	}
+	copy(buf[8:12], ip4)
+	binary.BigEndian.PutUint16(buf[12:14], uint16(port))
+	return buf
+}
+
+// advertisementCache 是所有扫描共享的 LANShare 广播缓存：ScanStream 的 advHandler
+// 每解出一条 LANShare 广播就按地址写入这里。Device.LANShare 直接取自当次解码的
+// 广播，不从该缓存读取。
+var advertisementCache = NewAdvertisementCache()
+
+// decodeLANShareAdvertisement 检查一条 BLE 广播里是否携带 LANShare 的
+// manufacturer-data，如果有则解码为 LANShareInfo。
+func decodeLANShareAdvertisement(a ble.Advertisement) (LANShareInfo, bool) {
+	md := a.ManufacturerData()
+	if len(md) < 2 {
+		return LANShareInfo{}, false
+	}
+	if binary.LittleEndian.Uint16(md[0:2]) != lanshareManufacturerID {
+		return LANShareInfo{}, false
+	}
+	return decodeManufacturerData(md[2:])
+}
+
+// decodeManufacturerData 解析 encodeManufacturerData 产出的载荷。
+func decodeManufacturerData(data []byte) (LANShareInfo, bool) {
+	if len(data) != 14 {
+		return LANShareInfo{}, false
+	}
+	var info LANShareInfo
+	copy(info.Fingerprint[:], data[0:8])
+	info.IP = net.IPv4(data[8], data[9], data[10], data[11])
+	info.Port = binary.BigEndian.Uint16(data[12:14])
+	info.LastSeen = time.Now()
+	return info, true
+}
+
+// Advertiser 把本机的 LANShare/WebDAV 实例以 BLE 外设（GATT Server + 广播）的
+// 形式对外暴露，使附近扫描中的手机无需接入 Wi-Fi 就能发现并获取连接信息。
+type Advertiser struct {
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	running bool
+}
+
+// NewAdvertiser 创建一个尚未启动的广播器。
+func NewAdvertiser() *Advertiser {
+	return &Advertiser{}
+}
+
+// Start 开始以 manufacturer-data 广播 LANShare 节点信息，并注册一个 GATT 服务，
+// 其特征分别暴露共享根路径、当前 IP 和一个用于配对的挑战随机数（nonce）。
+// 如果 tlsFingerprint 非空，还会额外暴露一个特征，让已配对过的对端用
+// trust-on-first-use 的方式核实自己连接的是同一个 TLS 证书。hostname 参与
+// manufacturer-data 指纹的计算，应当传入 os.Hostname() 的结果。
+func (a *Advertiser) Start(ctx context.Context, hostname string, root string, ip net.IP, port int, rootFingerprint string, nonce []byte, tlsFingerprint []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.running {
+		return fmt.Errorf("advertiser already running")
+	}
+
+	advCtx, cancel := context.WithCancel(ctx)
+
+	payload := encodeManufacturerData(hostname, port, rootFingerprint, ip)
+	md := make([]byte, 2+len(payload))
+	binary.LittleEndian.PutUint16(md[0:2], lanshareManufacturerID)
+	copy(md[2:], payload)
+
+	svc := ble.NewService(ble.MustParse(lanshareServiceUUID))
+	svc.AddCharacteristic(newReadOnlyChar(lanshareRootPathCharUUID, []byte(root)))
+	svc.AddCharacteristic(newReadOnlyChar(lanshareIPCharUUID, []byte(ip.String())))
+	svc.AddCharacteristic(newReadOnlyChar(lanshareNonceCharUUID, nonce))
+	if len(tlsFingerprint) > 0 {
+		svc.AddCharacteristic(newReadOnlyChar(lanshareTLSFingerprintCharUUID, tlsFingerprint))
+	}
+
+	if err := ble.AddService(svc); err != nil {
+		cancel()
+		return fmt.Errorf("add gatt service: %w", err)
+	}
+
+	go func() {
+		if err := ble.AdvertiseMfgData(advCtx, lanshareManufacturerID, payload); err != nil {
+			log.Printf("advertiser: stopped: %v", err)
+		}
+		a.mu.Lock()
+		a.running = false
+		a.mu.Unlock()
+	}()
+
+	a.cancel = cancel
+	a.running = true
+	return nil
+}
+
+// Stop 停止广播与 GATT 服务。
+func (a *Advertiser) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cancel != nil {
+		a.cancel()
+	}
+	a.running = false
+}
+
+const (
+	lanshareServiceUUID            = "6c616e73686172650000000000000000"
+	lanshareRootPathCharUUID       = "6c616e73686172650000000000000001"
+	lanshareIPCharUUID             = "6c616e73686172650000000000000002"
+	lanshareNonceCharUUID          = "6c616e73686172650000000000000003"
+	lanshareTLSFingerprintCharUUID = "6c616e73686172650000000000000004"
+)
+
+// newReadOnlyChar 构造一个只读、值固定的 GATT 特征。
+func newReadOnlyChar(uuid string, value []byte) *ble.Characteristic {
+	c := ble.NewCharacteristic(ble.MustParse(uuid))
+	c.HandleRead(ble.ReadHandlerFunc(func(req ble.Request, rsp ble.ResponseWriter) {
+		_, _ = rsp.Write(value)
+	}))
+	return c
+}
+
+// advCacheEntry 是广播缓存中的一条记录。
+type advCacheEntry struct {
+	info LANShareInfo
+}
+
+// AdvertisementCache 按设备地址缓存最近收到的 LANShare 广播，并清理超过
+// lanshareAdvTTL 未刷新的条目，避免缓存里堆积已离线的节点。
+type AdvertisementCache struct {
+	mu      sync.Mutex
+	entries map[string]advCacheEntry
+}
+
+// NewAdvertisementCache 创建一个空的广播缓存。
+func NewAdvertisementCache() *AdvertisementCache {
+	return &AdvertisementCache{entries: make(map[string]advCacheEntry)}
+}
+
+// Put 记录/刷新某个地址最新的 LANShare 广播信息。
+func (c *AdvertisementCache) Put(addr string, info LANShareInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[addr] = advCacheEntry{info: info}
+}
+
+// Get 返回某个地址最近一次见到的广播信息。
+func (c *AdvertisementCache) Get(addr string) (LANShareInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[addr]
+	return e.info, ok
+}
+
+// Prune 移除所有超过 lanshareAdvTTL 未刷新的条目。
+func (c *AdvertisementCache) Prune() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for addr, e := range c.entries {
+		if now.Sub(e.info.LastSeen) > lanshareAdvTTL {
+			delete(c.entries, addr)
+		}
+	}
+}
+
+// StartJanitor 周期性地调用 Prune，直到 ctx 被取消。
+func (c *AdvertisementCache) StartJanitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.Prune()
+			}
+		}
+	}()
+}
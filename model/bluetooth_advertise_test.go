@@ -0,0 +1,47 @@
+package model
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestManufacturerDataEncodeDecodeRoundTrip(t *testing.T) {
+	ip := net.ParseIP("192.168.1.42")
+	data := encodeManufacturerData("my-host", 8443, "deadbeef", ip)
+
+	info, ok := decodeManufacturerData(data)
+	if !ok {
+		t.Fatalf("decodeManufacturerData rejected a payload encodeManufacturerData produced")
+	}
+	if info.Port != 8443 {
+		t.Fatalf("port mismatch: got %d, want 8443", info.Port)
+	}
+	if !info.IP.Equal(ip.To4()) {
+		t.Fatalf("ip mismatch: got %s, want %s", info.IP, ip)
+	}
+
+	other := encodeManufacturerData("my-host", 8443, "00000000", ip)
+	otherInfo, ok := decodeManufacturerData(other)
+	if !ok {
+		t.Fatalf("decodeManufacturerData rejected second payload")
+	}
+	if !otherInfo.Changed(info) {
+		t.Fatalf("a different root fingerprint should produce a Changed() fingerprint")
+	}
+}
+
+func TestAdvertisementCacheJanitorPrunesStale(t *testing.T) {
+	c := NewAdvertisementCache()
+	c.Put("aa:bb:cc:dd:ee:ff", LANShareInfo{LastSeen: time.Now().Add(-10 * time.Minute)})
+	c.Put("11:22:33:44:55:66", LANShareInfo{LastSeen: time.Now()})
+
+	c.Prune()
+
+	if _, ok := c.Get("aa:bb:cc:dd:ee:ff"); ok {
+		t.Fatalf("expected stale entry to be pruned")
+	}
+	if _, ok := c.Get("11:22:33:44:55:66"); !ok {
+		t.Fatalf("expected fresh entry to survive pruning")
+	}
+}
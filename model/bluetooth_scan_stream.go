@@ -0,0 +1,179 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-ble/ble"
+	"github.com/go-ble/ble/examples/lib/dev"
+)
+
+// deviceLostAfter 是一个设备多久没有收到新的广播就被视为离线并发出 Lost 事件。
+const deviceLostAfter = 10 * time.Second
+
+// DeviceEventType 描述一次流式扫描事件的种类。
+type DeviceEventType string
+
+const (
+	DeviceAdded   DeviceEventType = "Added"
+	DeviceUpdated DeviceEventType = "Updated"
+	DeviceLost    DeviceEventType = "Lost"
+)
+
+// DeviceEvent 是 ScanStream 推送给订阅者的一条事件。
+type DeviceEvent struct {
+	Type   DeviceEventType `json:"type"`
+	Device Device          `json:"device"`
+}
+
+// scanState 是某个设备在 TTL 表中的状态，用于判断 Added/Updated/Lost。
+type scanState struct {
+	device   Device
+	lastSeen time.Time
+}
+
+// ScanStream 开始一次持续扫描，立即返回一个事件通道；调用方通过 StopScan
+// （或取消 ctx）结束扫描。相比 Scan(seconds) 的"攒够再返回"，它让前端可以
+// 实时看到设备上线、RSSI 更新、以及超过 deviceLostAfter 未见到广播后的下线。
+func (b *BluetoothService) ScanStream(ctx context.Context) (<-chan DeviceEvent, error) {
+	b.mu.Lock()
+	if b.scanCancel != nil {
+		b.mu.Unlock()
+		return nil, fmt.Errorf("scan already in progress")
+	}
+	scanCtx, cancel := context.WithCancel(ctx)
+	b.scanCancel = cancel
+	b.mu.Unlock()
+
+	d, err := dev.NewDevice("default")
+	if err != nil {
+		b.clearScanCancel()
+		return nil, fmt.Errorf("create BLE device: %w", err)
+	}
+	ble.SetDefaultDevice(d)
+
+	events := make(chan DeviceEvent, 32)
+	var stateMu sync.Mutex
+	state := make(map[string]scanState)
+
+	emit := func(ev DeviceEvent) {
+		select {
+		case events <- ev:
+		default:
+			log.Printf("ScanStream: event channel full, dropping %s event for %s", ev.Type, ev.Device.Addr)
+		}
+	}
+
+	advHandler := func(a ble.Advertisement) {
+		name := a.LocalName()
+		if name == "" {
+			return
+		}
+		addr := a.Addr().String()
+		dvc := Device{Addr: addr, Name: name, RSSI: a.RSSI()}
+		if info, ok := decodeLANShareAdvertisement(a); ok {
+			advertisementCache.Put(addr, info)
+			dvc.LANShare = &info
+		}
+
+		stateMu.Lock()
+		_, existed := state[addr]
+		state[addr] = scanState{device: dvc, lastSeen: time.Now()}
+		stateMu.Unlock()
+
+		if existed {
+			emit(DeviceEvent{Type: DeviceUpdated, Device: dvc})
+		} else {
+			emit(DeviceEvent{Type: DeviceAdded, Device: dvc})
+		}
+	}
+
+	var scanDone sync.WaitGroup
+	scanDone.Add(1)
+	go func() {
+		defer scanDone.Done()
+		if err := ble.Scan(scanCtx, true, func(a ble.Advertisement) { advHandler(a) }, nil); err != nil {
+			log.Printf("ScanStream: scan ended: %v", err)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(deviceLostAfter / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-scanCtx.Done():
+				// 等待扫描回调 goroutine 真正退出后再关闭 events，否则它仍可能
+				// 在我们关闭之后调用 emit 往已关闭的通道发送，引发 panic。
+				scanDone.Wait()
+				close(events)
+				b.clearScanCancel()
+				return
+			case <-ticker.C:
+				now := time.Now()
+				stateMu.Lock()
+				for addr, s := range state {
+					if now.Sub(s.lastSeen) > deviceLostAfter {
+						delete(state, addr)
+						stateMu.Unlock()
+						emit(DeviceEvent{Type: DeviceLost, Device: s.device})
+						stateMu.Lock()
+					}
+				}
+				stateMu.Unlock()
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// StopScan 结束一次通过 ScanStream 发起的扫描；没有扫描在进行时是空操作。
+func (b *BluetoothService) StopScan() {
+	b.mu.Lock()
+	cancel := b.scanCancel
+	b.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (b *BluetoothService) clearScanCancel() {
+	b.mu.Lock()
+	b.scanCancel = nil
+	b.mu.Unlock()
+}
+
+// ScanBatch 在给定秒数内收集 ScanStream 的事件并返回最终的设备快照，保留给
+// 调用方做向后兼容的"阻塞 N 秒"式扫描；Scan 本身就是它的薄封装。
+func (b *BluetoothService) ScanBatch(seconds int) ([]Device, error) {
+	if seconds <= 0 {
+		seconds = 5
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(seconds)*time.Second)
+	defer cancel()
+
+	events, err := b.ScanStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make(map[string]Device)
+	for ev := range events {
+		switch ev.Type {
+		case DeviceAdded, DeviceUpdated:
+			devices[ev.Device.Addr] = ev.Device
+		case DeviceLost:
+			delete(devices, ev.Device.Addr)
+		}
+	}
+
+	res := make([]Device, 0, len(devices))
+	for _, v := range devices {
+		res = append(res, v)
+	}
+	return res, nil
+}
@@ -0,0 +1,335 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ble/ble"
+)
+
+// SessionID 唯一标识一次蓝牙连接会话。
+type SessionID string
+
+// Nordic UART Service (NUS) 的标准 UUID，用作出站信令通道。
+const (
+	nusServiceUUID = "6e400001b5a3f393e0a9e50e24dcca9e"
+	nusRXCharUUID  = "6e400002b5a3f393e0a9e50e24dcca9e" // 手机 -> 本机（写）
+	nusTXCharUUID  = "6e400003b5a3f393e0a9e50e24dcca9e" // 本机 -> 手机（通知）
+)
+
+// EventType 描述一次会话事件的种类。
+type EventType string
+
+const (
+	EventNotify     EventType = "notify"
+	EventDisconnect EventType = "disconnect"
+)
+
+// SessionEvent 是通过 Events() 通道对外广播的会话事件。
+type SessionEvent struct {
+	Session  SessionID `json:"session"`
+	Type     EventType `json:"type"`
+	CharUUID string    `json:"charUuid,omitempty"`
+	Data     []byte    `json:"data,omitempty"`
+	Err      error     `json:"-"`
+}
+
+// connSession 保存单个已建立连接的状态。
+type connSession struct {
+	id     SessionID
+	addr   string
+	client ble.Client
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	subs       map[string]ble.Characteristic // charUUID -> characteristic，用于 Write/Subscribe 查找
+	subscribed map[string]bool               // 当前已开启通知的 charUUID，重连后用于恢复订阅
+}
+
+// getClient 以加锁方式读取当前底层连接，避免与 watchDisconnect 重连时
+// 对 client 字段的写入发生数据竞争。
+func (s *connSession) getClient() ble.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+// setClient 以加锁方式替换底层连接，重连成功后调用。
+func (s *connSession) setClient(c ble.Client) {
+	s.mu.Lock()
+	s.client = c
+	s.mu.Unlock()
+}
+
+// ConnManager 维护按 MAC 地址索引的连接会话注册表，并支持自动重连与事件广播。
+// 这是 BluetoothService 的连接态扩展，与无连接的 Scan 逻辑分开维护。
+type ConnManager struct {
+	mu       sync.Mutex
+	sessions map[SessionID]*connSession
+	byAddr   map[string]SessionID
+
+	events chan SessionEvent
+}
+
+// NewConnManager 创建一个空的连接管理器。
+func NewConnManager() *ConnManager {
+	return &ConnManager{
+		sessions: make(map[SessionID]*connSession),
+		byAddr:   make(map[string]SessionID),
+		events:   make(chan SessionEvent, 64),
+	}
+}
+
+// Events 返回一个只读通道，Wails 前端可订阅 notify/disconnect 事件。
+func (m *ConnManager) Events() <-chan SessionEvent {
+	return m.events
+}
+
+func (m *ConnManager) emit(ev SessionEvent) {
+	select {
+	case m.events <- ev:
+	default:
+		log.Printf("conn manager: event channel full, dropping %s event for %s", ev.Type, ev.Session)
+	}
+}
+
+// recoverableDialErrSubstrings 是值得自动重试的典型 BlueZ 连接错误。
+var recoverableDialErrSubstrings = []string{
+	"Software caused connection abort",
+	"connection timed out",
+	"le-connection-abort-by-local",
+}
+
+// isRecoverableDialErr 判断典型 BlueZ 连接错误是否值得自动重试。
+func isRecoverableDialErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, sub := range recoverableDialErrSubstrings {
+		if strings.Contains(msg, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// Connect 拨号连接到给定地址的外设，超时后放弃。连接成功的会话会被注册进
+// byAddr/sessions 表，并在底层连接断开时自动按退避策略重连。
+func (m *ConnManager) Connect(addr string, timeout time.Duration) (SessionID, error) {
+	if addr == "" {
+		return "", errors.New("addr is required")
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	dialCtx, cancelDial := context.WithTimeout(context.Background(), timeout)
+	defer cancelDial()
+
+	client, err := ble.Dial(dialCtx, ble.NewAddr(addr))
+	if err != nil {
+		return "", fmt.Errorf("dial %s: %w", addr, err)
+	}
+
+	sid := SessionID(fmt.Sprintf("%s-%d", addr, time.Now().UnixNano()))
+	ctx, cancel := context.WithCancel(context.Background())
+	sess := &connSession{
+		id:         sid,
+		addr:       addr,
+		client:     client,
+		cancel:     cancel,
+		subs:       make(map[string]ble.Characteristic),
+		subscribed: make(map[string]bool),
+	}
+
+	m.mu.Lock()
+	m.sessions[sid] = sess
+	m.byAddr[addr] = sid
+	m.mu.Unlock()
+
+	go m.watchDisconnect(ctx, sess)
+
+	return sid, nil
+}
+
+// watchDisconnect 阻塞等待底层连接关闭，通知订阅者并尝试按退避策略重连。
+func (m *ConnManager) watchDisconnect(ctx context.Context, sess *connSession) {
+	<-sess.getClient().Disconnected()
+	m.emit(SessionEvent{Session: sess.id, Type: EventDisconnect})
+
+	select {
+	case <-ctx.Done():
+		// Disconnect() 已主动撤销该会话，不再重连
+		return
+	default:
+	}
+
+	backoff := time.Second
+	for attempt := 1; attempt <= 5; attempt++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		dialCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		client, err := ble.Dial(dialCtx, ble.NewAddr(sess.addr))
+		cancel()
+		if err == nil {
+			sess.setClient(client)
+			log.Printf("session %s: reconnected to %s after %d attempt(s)", sess.id, sess.addr, attempt)
+			if err := m.restoreSubscriptions(sess); err != nil {
+				log.Printf("session %s: failed to restore subscriptions after reconnect: %v", sess.id, err)
+			}
+			go m.watchDisconnect(ctx, sess)
+			return
+		}
+		if !isRecoverableDialErr(err) {
+			log.Printf("session %s: giving up reconnect to %s: %v", sess.id, sess.addr, err)
+			return
+		}
+		backoff *= 2
+	}
+	log.Printf("session %s: exhausted reconnect attempts to %s", sess.id, sess.addr)
+}
+
+// restoreSubscriptions 在重连后重新发现 GATT 服务，并对重连前已经订阅过通知的
+// 特征重新 Subscribe，避免 sess.subs 里残留旧连接的 GATT handle 导致 NUS
+// 通知静默失效。
+func (m *ConnManager) restoreSubscriptions(sess *connSession) error {
+	if _, err := m.DiscoverServices(sess.id); err != nil {
+		return fmt.Errorf("rediscover services: %w", err)
+	}
+
+	sess.mu.Lock()
+	charUUIDs := make([]string, 0, len(sess.subscribed))
+	for charUUID := range sess.subscribed {
+		charUUIDs = append(charUUIDs, charUUID)
+	}
+	sess.mu.Unlock()
+
+	var lastErr error
+	for _, charUUID := range charUUIDs {
+		if err := m.Subscribe(sess.id, charUUID); err != nil {
+			lastErr = fmt.Errorf("resubscribe %s: %w", charUUID, err)
+		}
+	}
+	return lastErr
+}
+
+func (m *ConnManager) get(sid SessionID) (*connSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sess, ok := m.sessions[sid]
+	if !ok {
+		return nil, fmt.Errorf("unknown session %s", sid)
+	}
+	return sess, nil
+}
+
+// DiscoverServices 枚举已连接外设的 GATT 服务与特征。
+func (m *ConnManager) DiscoverServices(sid SessionID) ([]*ble.Service, error) {
+	sess, err := m.get(sid)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := sess.getClient().DiscoverProfile(true)
+	if err != nil {
+		return nil, fmt.Errorf("discover profile: %w", err)
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	for _, svc := range profile.Services {
+		for _, c := range svc.Characteristics {
+			sess.subs[c.UUID.String()] = *c
+		}
+	}
+	return profile.Services, nil
+}
+
+// Subscribe 对指定特征开启通知，收到的数据以 notify 事件广播出去。
+func (m *ConnManager) Subscribe(sid SessionID, charUUID string) error {
+	sess, err := m.get(sid)
+	if err != nil {
+		return err
+	}
+	sess.mu.Lock()
+	c, ok := sess.subs[charUUID]
+	sess.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("characteristic %s not discovered for session %s", charUUID, sid)
+	}
+
+	if err := sess.getClient().Subscribe(&c, false, func(data []byte) {
+		m.emit(SessionEvent{Session: sid, Type: EventNotify, CharUUID: charUUID, Data: data})
+	}); err != nil {
+		return err
+	}
+
+	sess.mu.Lock()
+	sess.subscribed[charUUID] = true
+	sess.mu.Unlock()
+	return nil
+}
+
+// Write 向指定特征写入数据（默认要求写确认）。
+func (m *ConnManager) Write(sid SessionID, charUUID string, data []byte) error {
+	sess, err := m.get(sid)
+	if err != nil {
+		return err
+	}
+	sess.mu.Lock()
+	c, ok := sess.subs[charUUID]
+	sess.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("characteristic %s not discovered for session %s", charUUID, sid)
+	}
+	return sess.getClient().WriteCharacteristic(&c, data, false)
+}
+
+// Disconnect 主动断开会话并从注册表中移除，不会触发自动重连。
+func (m *ConnManager) Disconnect(sid SessionID) error {
+	sess, err := m.get(sid)
+	if err != nil {
+		return err
+	}
+	sess.cancel()
+	err = sess.getClient().CancelConnection()
+
+	m.mu.Lock()
+	delete(m.sessions, sid)
+	delete(m.byAddr, sess.addr)
+	m.mu.Unlock()
+
+	return err
+}
+
+// ConnectNUS 是 Connect 的便捷封装：连接后自动发现服务并订阅 Nordic UART
+// 的 TX 特征，返回的会话可直接用 WriteNUS 发送数据到 RX 特征。
+func (m *ConnManager) ConnectNUS(addr string, timeout time.Duration) (SessionID, error) {
+	sid, err := m.Connect(addr, timeout)
+	if err != nil {
+		return "", err
+	}
+	if _, err := m.DiscoverServices(sid); err != nil {
+		_ = m.Disconnect(sid)
+		return "", err
+	}
+	if err := m.Subscribe(sid, nusTXCharUUID); err != nil {
+		_ = m.Disconnect(sid)
+		return "", err
+	}
+	return sid, nil
+}
+
+// WriteNUS 向 Nordic UART 的 RX 特征写入数据。
+func (m *ConnManager) WriteNUS(sid SessionID, data []byte) error {
+	return m.Write(sid, nusRXCharUUID, data)
+}
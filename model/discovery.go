@@ -0,0 +1,259 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/grandcat/zeroconf"
+)
+
+// webdavServiceType 是标准的 WebDAV mDNS 服务类型。
+const webdavServiceType = "_webdav._tcp"
+
+// lanshareServiceType 是 LANShare 自己的服务类型，与 _webdav._tcp 一起发布，
+// 便于只关心 LANShare 节点（而非任意 WebDAV 服务器）的客户端直接过滤。
+const lanshareServiceType = "_lanshare._tcp"
+
+const mdnsDomain = "local."
+
+// Peer 表示一个通过 mDNS 发现的 LANShare/WebDAV 节点。
+type Peer struct {
+	NodeID         string    `json:"nodeId"`
+	Name           string    `json:"name"`
+	Host           string    `json:"host"`
+	IP             net.IP    `json:"ip"`
+	Port           int       `json:"port"`
+	Path           string    `json:"path"`
+	Version        string    `json:"version"`
+	TLSFingerprint string    `json:"tlsFingerprint,omitempty"`
+	Seen           time.Time `json:"seen"`
+}
+
+// Discovery 把本机的 WebDAVService 发布为 mDNS 记录，并浏览局域网上的其他
+// LANShare 节点，为前端提供一个无需手动输入 IP 的配对列表。
+type Discovery struct {
+	nodeID         string
+	version        string
+	tlsFingerprint string // 十六进制，留空表示未启用 TLS
+
+	mu           sync.Mutex
+	server       *zeroconf.Server
+	webdavServer *zeroconf.Server
+	peers        map[string]Peer
+	changed      chan struct{}
+	cancel       context.CancelFunc
+}
+
+// SetTLSFingerprint 把当前 TLS 证书的指纹（十六进制）加入 TXT 记录，便于对端
+// 在连接前核实 trust-on-first-use 的证书没有变化。必须在 Start 之前调用。
+func (d *Discovery) SetTLSFingerprint(hexFingerprint string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tlsFingerprint = hexFingerprint
+}
+
+// NewDiscovery 创建一个尚未启动的 Discovery。nodeID 应当是稳定的节点标识
+// （例如 overlay 的 160-bit 节点 ID 的十六进制表示），version 是展示用的版本号。
+func NewDiscovery(nodeID, version string) *Discovery {
+	return &Discovery{
+		nodeID:  nodeID,
+		version: version,
+		peers:   make(map[string]Peer),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// Start 发布本机的 _webdav._tcp / _lanshare._tcp 记录并开始浏览局域网，
+// 同时监听网卡变化以便在活动网卡切换时重新公告。
+func (d *Discovery) Start(ctx context.Context, port int, sharedPath string) error {
+	d.mu.Lock()
+	if d.cancel != nil {
+		d.mu.Unlock()
+		return fmt.Errorf("discovery already running")
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	d.cancel = cancel
+	d.mu.Unlock()
+
+	if err := d.announce(port, sharedPath); err != nil {
+		return err
+	}
+
+	go d.browse(runCtx)
+	go d.watchInterfaces(runCtx, port, sharedPath)
+
+	return nil
+}
+
+// txtRecords 构造公告里的 TXT 条目。
+func (d *Discovery) txtRecords(sharedPath string) []string {
+	txt := []string{
+		fmt.Sprintf("path=%s", sharedPath),
+		fmt.Sprintf("version=%s", d.version),
+		fmt.Sprintf("nodeID=%s", d.nodeID),
+	}
+	if d.tlsFingerprint != "" {
+		txt = append(txt, fmt.Sprintf("tlsFingerprint=%s", d.tlsFingerprint))
+	}
+	return txt
+}
+
+// announce 注册（或者在网卡变化后重新注册）mDNS 服务记录，同时发布标准的
+// _webdav._tcp（便于通用 WebDAV 客户端发现）和自定义的 _lanshare._tcp
+// （便于 LANShare 客户端只过滤自己的节点）两条记录。
+func (d *Discovery) announce(port int, sharedPath string) error {
+	server, err := zeroconf.Register(d.nodeID, lanshareServiceType, mdnsDomain, port, d.txtRecords(sharedPath), nil)
+	if err != nil {
+		return fmt.Errorf("register %s: %w", lanshareServiceType, err)
+	}
+	webdavServer, err := zeroconf.Register(d.nodeID, webdavServiceType, mdnsDomain, port, d.txtRecords(sharedPath), nil)
+	if err != nil {
+		server.Shutdown()
+		return fmt.Errorf("register %s: %w", webdavServiceType, err)
+	}
+
+	d.mu.Lock()
+	if d.server != nil {
+		d.server.Shutdown()
+	}
+	if d.webdavServer != nil {
+		d.webdavServer.Shutdown()
+	}
+	d.server = server
+	d.webdavServer = webdavServer
+	d.mu.Unlock()
+
+	log.Printf("discovery: announcing node %s on port %d", d.nodeID, port)
+	return nil
+}
+
+// browse 持续浏览 _lanshare._tcp，把结果汇入 peers 表并在变化时通知 changed 通道。
+func (d *Discovery) browse(ctx context.Context) {
+	resolver, err := zeroconf.NewResolver(nil)
+	if err != nil {
+		log.Printf("discovery: failed to create resolver: %v", err)
+		return
+	}
+
+	entries := make(chan *zeroconf.ServiceEntry, 16)
+	go func() {
+		for entry := range entries {
+			d.handleEntry(entry)
+		}
+	}()
+
+	for {
+		if err := resolver.Browse(ctx, lanshareServiceType, mdnsDomain, entries); err != nil {
+			log.Printf("discovery: browse error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			close(entries)
+			return
+		case <-time.After(30 * time.Second):
+			// 重新发起一轮浏览，弥补部分网络环境下单次 Browse 收不全的问题
+		}
+	}
+}
+
+func (d *Discovery) handleEntry(entry *zeroconf.ServiceEntry) {
+	peer := Peer{
+		Name:    entry.Instance,
+		Host:    entry.HostName,
+		Port:    entry.Port,
+		Version: "",
+		Seen:    time.Now(),
+	}
+	for _, txt := range entry.Text {
+		switch {
+		case hasPrefix(txt, "path="):
+			peer.Path = txt[len("path="):]
+		case hasPrefix(txt, "version="):
+			peer.Version = txt[len("version="):]
+		case hasPrefix(txt, "nodeID="):
+			peer.NodeID = txt[len("nodeID="):]
+		case hasPrefix(txt, "tlsFingerprint="):
+			peer.TLSFingerprint = txt[len("tlsFingerprint="):]
+		}
+	}
+	if len(entry.AddrIPv4) > 0 {
+		peer.IP = entry.AddrIPv4[0]
+	}
+	if peer.NodeID == "" || peer.NodeID == d.nodeID {
+		return // 忽略没有节点 ID 的记录，以及自己
+	}
+
+	d.mu.Lock()
+	d.peers[peer.NodeID] = peer
+	d.mu.Unlock()
+
+	select {
+	case d.changed <- struct{}{}:
+	default:
+	}
+}
+
+func hasPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+}
+
+// watchInterfaces 定期检查 localIPv4() 选中的网卡是否变化，变化时重新公告，
+// 复用与 WebDAVService 相同的网卡遍历逻辑，保证两者对"当前活动网卡"的判断一致。
+func (d *Discovery) watchInterfaces(ctx context.Context, port int, sharedPath string) {
+	current := localIPv4()
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ip := localIPv4(); ip != current {
+				log.Printf("discovery: active interface changed %s -> %s, re-announcing", current, ip)
+				current = ip
+				if err := d.announce(port, sharedPath); err != nil {
+					log.Printf("discovery: re-announce failed: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// Peers 返回当前已知的对端列表快照。
+func (d *Discovery) Peers() []Peer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	res := make([]Peer, 0, len(d.peers))
+	for _, p := range d.peers {
+		res = append(res, p)
+	}
+	return res
+}
+
+// Changed 返回一个通道，每当 Peers() 的结果发生变化时会收到一个通知，供
+// Wails 前端刷新列表。
+func (d *Discovery) Changed() <-chan struct{} {
+	return d.changed
+}
+
+// Stop 撤销 mDNS 公告并停止浏览。
+func (d *Discovery) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cancel != nil {
+		d.cancel()
+		d.cancel = nil
+	}
+	if d.server != nil {
+		d.server.Shutdown()
+		d.server = nil
+	}
+	if d.webdavServer != nil {
+		d.webdavServer.Shutdown()
+		d.webdavServer = nil
+	}
+}
@@ -0,0 +1,503 @@
+package overlay
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	alpha            = 3 // 并发查询度
+	rpcTimeout       = 3 * time.Second
+	refreshInterval  = time.Hour
+	defaultRecordTTL = 24 * time.Hour
+)
+
+// storedValue 是本地为其他节点存储的一条 (key -> record) 记录，带过期时间。
+type storedValue struct {
+	value  []byte
+	expiry time.Time
+}
+
+// pendingCall 是一次等待回复的未完成 RPC，记录了这次 RPC 实际发往的节点，
+// 以便校验收到的回复确实来自被联系的那个节点，而不是任何抢先猜中
+// requestID 的第三方。
+type pendingCall struct {
+	ch         chan message
+	targetID   NodeID
+	targetAddr *net.UDPAddr
+}
+
+// matches 判断一条回复是否确实来自这次 RPC 联系的节点：UDP 报文的实际来源
+// 地址必须与发出请求时拨打的目标地址一致（这是不知道对方节点 ID 时——例如
+// 首次联系一个 bootstrap 种子——仍然成立的最小保证）；如果发起调用时已经
+// 知道目标节点 ID（targetID 非零值），还要求消息里自称的节点 ID 与之一致。
+func (c pendingCall) matches(fromID NodeID, from *net.UDPAddr) bool {
+	if from == nil || c.targetAddr == nil {
+		return false
+	}
+	if !from.IP.Equal(c.targetAddr.IP) || from.Port != c.targetAddr.Port {
+		return false
+	}
+	var zero NodeID
+	if c.targetID != zero && fromID != c.targetID {
+		return false
+	}
+	return true
+}
+
+// DHT 是单个节点参与的 Kademlia 覆盖网络客户端/服务端。它通过 UDP 监听
+// PING/STORE/FIND_NODE/FIND_VALUE 四种 RPC，维护一张按 XOR 距离分桶的路由表，
+// 并对外提供 LookupPeer/NearestPeers 供上层（WebDAVService）查询。
+type DHT struct {
+	self Contact
+	priv ed25519.PrivateKey
+
+	conn  *net.UDPConn
+	table *RoutingTable
+
+	mu      sync.Mutex
+	store   map[NodeID]storedValue
+	seen    map[NodeID]uint64 // 对端节点 ID -> 已见过的最大 nonce，用于重放保护
+	nonce   uint64            // 本节点发出报文使用的单调递增 nonce
+	counter uint64            // 请求 ID 计数器
+
+	pendingMu sync.Mutex
+	pending   map[requestID]pendingCall
+
+	closed int32
+}
+
+// NewDHT 创建一个尚未开始监听的 DHT 节点。priv 是该节点的 Ed25519 私钥，
+// 其公钥的 SHA-1 摘要即为节点 ID；webdavPort 会被放进对外的 Contact，
+// 供其他节点在 LookupPeer 后直接去连 WebDAV。
+func NewDHT(priv ed25519.PrivateKey, ip net.IP, port, webdavPort int) *DHT {
+	pub := priv.Public().(ed25519.PublicKey)
+	self := Contact{
+		ID:         NewNodeID(pub),
+		IP:         ip,
+		Port:       port,
+		WebDAVPort: webdavPort,
+		PublicKey:  pub,
+	}
+	return &DHT{
+		self:    self,
+		priv:    priv,
+		table:   NewRoutingTable(self.ID),
+		store:   make(map[NodeID]storedValue),
+		seen:    make(map[NodeID]uint64),
+		pending: make(map[requestID]pendingCall),
+	}
+}
+
+// Self 返回本节点对外的 Contact 信息。
+func (d *DHT) Self() Contact { return d.self }
+
+// ListenAndServe 开始在本节点的 UDP 端口上接收请求，并启动 bucket 刷新循环，
+// 直到 ctx 被取消。
+func (d *DHT) ListenAndServe(ctx context.Context) error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: d.self.IP, Port: d.self.Port})
+	if err != nil {
+		return fmt.Errorf("listen udp: %w", err)
+	}
+	d.conn = conn
+	if d.self.Port == 0 {
+		d.self.Port = conn.LocalAddr().(*net.UDPAddr).Port
+	}
+
+	go d.serve(ctx)
+	go d.refreshLoop(ctx)
+	go d.expireLoop(ctx)
+
+	go func() {
+		<-ctx.Done()
+		atomic.StoreInt32(&d.closed, 1)
+		_ = conn.Close()
+	}()
+
+	return nil
+}
+
+func (d *DHT) serve(ctx context.Context) {
+	buf := make([]byte, 8192)
+	for {
+		n, addr, err := d.conn.ReadFromUDP(buf)
+		if err != nil {
+			if atomic.LoadInt32(&d.closed) == 1 {
+				return
+			}
+			log.Printf("overlay: read error: %v", err)
+			continue
+		}
+		msgBytes := make([]byte, n)
+		copy(msgBytes, buf[:n])
+		go d.handle(msgBytes, addr)
+	}
+}
+
+func (d *DHT) handle(raw []byte, from *net.UDPAddr) {
+	m, err := decodeMessage(raw)
+	if err != nil {
+		log.Printf("overlay: malformed message from %s: %v", from, err)
+		return
+	}
+	if err := m.verify(); err != nil {
+		log.Printf("overlay: rejecting message: %v", err)
+		return
+	}
+	if !d.checkAndUpdateNonce(m.From.ID, m.Nonce) {
+		log.Printf("overlay: rejecting replayed/out-of-order nonce from %s", m.From.ID)
+		return
+	}
+
+	switch m.Type {
+	case rpcPing:
+		d.witness(m.From)
+		d.reply(from, message{Type: rpcPong, RequestID: m.RequestID})
+	case rpcStore:
+		d.witness(m.From)
+		if m.Key != nil {
+			ttl := time.Duration(m.TTLMs) * time.Millisecond
+			if ttl <= 0 || ttl > defaultRecordTTL {
+				ttl = defaultRecordTTL
+			}
+			d.mu.Lock()
+			d.store[*m.Key] = storedValue{value: m.Value, expiry: time.Now().Add(ttl)}
+			d.mu.Unlock()
+		}
+		d.reply(from, message{Type: rpcStoreAck, RequestID: m.RequestID})
+	case rpcFindNode:
+		d.witness(m.From)
+		if m.Target != nil {
+			d.reply(from, message{Type: rpcNodes, RequestID: m.RequestID, Closest: d.table.Closest(*m.Target, bucketSize)})
+		}
+	case rpcFindValue:
+		d.witness(m.From)
+		if m.Target != nil {
+			d.mu.Lock()
+			v, ok := d.store[*m.Target]
+			d.mu.Unlock()
+			if ok && time.Now().Before(v.expiry) {
+				d.reply(from, message{Type: rpcValue, RequestID: m.RequestID, Key: m.Target, Value: v.value})
+			} else {
+				d.reply(from, message{Type: rpcNodes, RequestID: m.RequestID, Closest: d.table.Closest(*m.Target, bucketSize)})
+			}
+		}
+	case rpcPong, rpcStoreAck, rpcNodes, rpcValue:
+		// 回复只按 requestID 匹配是不够的：requestID 是一个可预测的小计数器，
+		// 任何人都可能在真正的被联系节点之前抢先伪造一条回复。deliver 会
+		// 校验发来这条回复的节点确实是这次 RPC 联系的目标，校验通过才会把
+		// 它记入路由表（witness）并唤醒等待者。
+		d.deliver(m, from)
+	default:
+		log.Printf("overlay: unknown rpc type %q from %s", m.Type, m.From.ID)
+	}
+}
+
+// checkAndUpdateNonce 实现重放保护：要求同一对端的 nonce 严格单调递增。
+func (d *DHT) checkAndUpdateNonce(peer NodeID, nonce uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	last, ok := d.seen[peer]
+	if ok && nonce <= last {
+		return false
+	}
+	d.seen[peer] = nonce
+	return true
+}
+
+// witness 把一次成功校验的来访消息记入路由表，必要时驱逐最久未联系的节点。
+func (d *DHT) witness(c Contact) {
+	if d.table.Add(c) {
+		return
+	}
+	oldest, ok := d.table.Oldest(c.ID)
+	if !ok {
+		return
+	}
+	if _, err := d.call(oldest, message{Type: rpcPing}); err != nil {
+		d.table.Replace(c)
+	}
+}
+
+func (d *DHT) deliver(m message, from *net.UDPAddr) {
+	d.pendingMu.Lock()
+	call, ok := d.pending[m.RequestID]
+	d.pendingMu.Unlock()
+	if !ok {
+		return
+	}
+	if !call.matches(m.From.ID, from) {
+		log.Printf("overlay: dropping %s reply for request %x: sender %s (%s) does not match contacted node %s (%s)",
+			m.Type, m.RequestID, m.From.ID, from, call.targetID, call.targetAddr)
+		return
+	}
+	d.witness(m.From)
+	select {
+	case call.ch <- m:
+	default:
+	}
+}
+
+func (d *DHT) reply(to *net.UDPAddr, m message) {
+	m.From = d.self
+	m.Nonce = atomic.AddUint64(&d.nonce, 1)
+	if err := m.sign(d.priv); err != nil {
+		log.Printf("overlay: failed to sign reply: %v", err)
+		return
+	}
+	b, err := encodeMessage(m)
+	if err != nil {
+		log.Printf("overlay: failed to encode reply: %v", err)
+		return
+	}
+	if _, err := d.conn.WriteToUDP(b, to); err != nil {
+		log.Printf("overlay: failed to send reply to %s: %v", to, err)
+	}
+}
+
+// call 向目标节点发出一次 RPC 并等待匹配的回复，超时则返回 error。
+func (d *DHT) call(target Contact, m message) (message, error) {
+	reqID := newRequestID(atomic.AddUint64(&d.counter, 1))
+	m.RequestID = reqID
+	m.From = d.self
+	m.Nonce = atomic.AddUint64(&d.nonce, 1)
+	if err := m.sign(d.priv); err != nil {
+		return message{}, err
+	}
+	b, err := encodeMessage(m)
+	if err != nil {
+		return message{}, err
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", target.Addr())
+	if err != nil {
+		return message{}, fmt.Errorf("resolve %s: %w", target.Addr(), err)
+	}
+
+	ch := make(chan message, 1)
+	d.pendingMu.Lock()
+	d.pending[reqID] = pendingCall{ch: ch, targetID: target.ID, targetAddr: addr}
+	d.pendingMu.Unlock()
+	defer func() {
+		d.pendingMu.Lock()
+		delete(d.pending, reqID)
+		d.pendingMu.Unlock()
+	}()
+
+	if _, err := d.conn.WriteToUDP(b, addr); err != nil {
+		return message{}, fmt.Errorf("send to %s: %w", target.Addr(), err)
+	}
+
+	select {
+	case reply := <-ch:
+		d.table.Add(target)
+		return reply, nil
+	case <-time.After(rpcTimeout):
+		return message{}, fmt.Errorf("rpc to %s timed out", target.ID)
+	}
+}
+
+// Bootstrap 联系一批种子地址，再以本节点 ID 为目标迭代 FIND_NODE，借此填充路由表。
+func (d *DHT) Bootstrap(ctx context.Context, seeds []string) error {
+	var contacted int
+	for _, addr := range seeds {
+		udpAddr, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			log.Printf("overlay: bad seed address %q: %v", addr, err)
+			continue
+		}
+		seed := Contact{IP: udpAddr.IP, Port: udpAddr.Port}
+		reply, err := d.call(seed, message{Type: rpcFindNode, Target: &d.self.ID})
+		if err != nil {
+			log.Printf("overlay: seed %q unreachable: %v", addr, err)
+			continue
+		}
+		contacted++
+		for _, c := range reply.Closest {
+			d.table.Add(c)
+		}
+	}
+	if contacted == 0 && len(seeds) > 0 {
+		return fmt.Errorf("failed to contact any of %d seed(s)", len(seeds))
+	}
+	_, err := d.FindNode(ctx, d.self.ID)
+	return err
+}
+
+// FindNode 迭代查询离 target 最近的节点，并把沿途发现的联系人记入路由表。
+func (d *DHT) FindNode(ctx context.Context, target NodeID) ([]Contact, error) {
+	return d.iterate(ctx, target)
+}
+
+// iterate 是 FIND_NODE 的 Kademlia 迭代查询：每轮向最近的 alpha 个尚未
+// 查询过的节点发起 FIND_NODE，直到某一轮的回复里没有任何新联系人，或者
+// 最近的 bucketSize 个节点都已查询过为止。
+func (d *DHT) iterate(ctx context.Context, target NodeID) ([]Contact, error) {
+	queried := make(map[NodeID]bool)
+	shortlist := d.table.Closest(target, bucketSize)
+
+	for {
+		var candidates []Contact
+		for _, c := range shortlist {
+			if !queried[c.ID] {
+				candidates = append(candidates, c)
+			}
+			if len(candidates) >= alpha {
+				break
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+
+		type result struct {
+			contacts []Contact
+		}
+		results := make(chan result, len(candidates))
+		for _, c := range candidates {
+			queried[c.ID] = true
+			go func(c Contact) {
+				reply, err := d.call(c, message{Type: rpcFindNode, Target: &target})
+				if err != nil {
+					results <- result{}
+					return
+				}
+				results <- result{contacts: reply.Closest}
+			}(c)
+		}
+
+		improved := false
+		for range candidates {
+			select {
+			case <-ctx.Done():
+				return shortlist, ctx.Err()
+			case r := <-results:
+				for _, c := range r.contacts {
+					if !containsID(shortlist, c.ID) {
+						shortlist = append(shortlist, c)
+						improved = true
+					}
+				}
+			}
+		}
+		sortByDistance(shortlist, target)
+		if len(shortlist) > bucketSize {
+			shortlist = shortlist[:bucketSize]
+		}
+		if !improved {
+			break
+		}
+	}
+	return shortlist, nil
+}
+
+func containsID(contacts []Contact, id NodeID) bool {
+	for _, c := range contacts {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Store 把 (key -> record) 复制到离 key 最近的若干节点上，TTL 到期后那些
+// 节点会自行丢弃该记录。
+func (d *DHT) Store(ctx context.Context, key NodeID, record Record, ttl time.Duration) error {
+	closest, err := d.FindNode(ctx, key)
+	if err != nil {
+		return err
+	}
+	value, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	stored := 0
+	for _, c := range closest {
+		_, err := d.call(c, message{Type: rpcStore, Key: &key, Value: value, TTLMs: ttl.Milliseconds()})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		stored++
+	}
+	if stored == 0 && lastErr != nil {
+		return fmt.Errorf("store failed on all %d candidate(s): %w", len(closest), lastErr)
+	}
+	return nil
+}
+
+// LookupPeer 在 DHT 中查找某个节点 ID 对应的 {ip, webdavPort, publicKey} 记录。
+func (d *DHT) LookupPeer(ctx context.Context, nodeID NodeID) (Record, bool, error) {
+	closest, err := d.FindNode(ctx, nodeID)
+	if err != nil {
+		return Record{}, false, err
+	}
+	for _, c := range closest {
+		reply, err := d.call(c, message{Type: rpcFindValue, Target: &nodeID})
+		if err != nil {
+			continue
+		}
+		if reply.Type == rpcValue {
+			rec, err := decodeRecord(reply.Value)
+			if err != nil {
+				continue
+			}
+			return rec, true, nil
+		}
+	}
+	return Record{}, false, nil
+}
+
+// NearestPeers 返回路由表中已知的、离本节点最近的 n 个联系人。
+func (d *DHT) NearestPeers(n int) []Contact {
+	return d.table.Closest(d.self.ID, n)
+}
+
+// refreshLoop 每隔 refreshInterval 对每个非空 bucket，以落在该 bucket 距离
+// 范围内的一个随机 ID 为目标做一次 FIND_NODE，以防长期没有查询流量的 bucket
+// 里的联系人悄悄过期。
+func (d *DHT) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, idx := range d.table.BucketIndexes() {
+				target := RandomIDInBucket(d.self.ID, idx)
+				if _, err := d.FindNode(ctx, target); err != nil {
+					log.Printf("overlay: bucket %d refresh failed: %v", idx, err)
+				}
+			}
+		}
+	}
+}
+
+// expireLoop 周期性清理本地为其他节点暂存的、已过 TTL 的记录。
+func (d *DHT) expireLoop(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			now := time.Now()
+			for k, v := range d.store {
+				if now.After(v.expiry) {
+					delete(d.store, k)
+				}
+			}
+			d.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package overlay
+
+import (
+	"crypto/ed25519"
+	"net"
+	"testing"
+)
+
+func newTestDHT(t *testing.T) *DHT {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return NewDHT(priv, net.ParseIP("127.0.0.1"), 0, 0)
+}
+
+func TestCheckAndUpdateNonceRejectsReplay(t *testing.T) {
+	d := newTestDHT(t)
+	var peer NodeID
+	peer[0] = 1
+
+	if !d.checkAndUpdateNonce(peer, 1) {
+		t.Fatalf("first nonce should be accepted")
+	}
+	if d.checkAndUpdateNonce(peer, 1) {
+		t.Fatalf("replayed nonce must be rejected")
+	}
+	if d.checkAndUpdateNonce(peer, 0) {
+		t.Fatalf("out-of-order (non-increasing) nonce must be rejected")
+	}
+	if !d.checkAndUpdateNonce(peer, 2) {
+		t.Fatalf("strictly increasing nonce should be accepted")
+	}
+}
+
+func TestPendingCallMatchesRejectsSpoofedSender(t *testing.T) {
+	realAddr := &net.UDPAddr{IP: net.ParseIP("10.0.0.5"), Port: 9000}
+	attackerAddr := &net.UDPAddr{IP: net.ParseIP("10.0.0.66"), Port: 9000}
+
+	var targetID, attackerID NodeID
+	targetID[0] = 1
+	attackerID[0] = 2
+
+	call := pendingCall{targetID: targetID, targetAddr: realAddr}
+
+	if !call.matches(targetID, realAddr) {
+		t.Fatalf("a reply from the actual contacted node/address should match")
+	}
+	if call.matches(attackerID, realAddr) {
+		t.Fatalf("a reply claiming a different node ID must not match even from the right address")
+	}
+	if call.matches(targetID, attackerAddr) {
+		t.Fatalf("a reply from a spoofed UDP source address must not match")
+	}
+}
+
+func TestPendingCallMatchesAllowsUnknownIDBySeedAddress(t *testing.T) {
+	// Bootstrap 联系种子节点时还不知道对方的节点 ID（targetID 是零值），
+	// 此时只能、也应当靠地址来绑定回复。
+	seedAddr := &net.UDPAddr{IP: net.ParseIP("10.0.0.5"), Port: 9000}
+	call := pendingCall{targetAddr: seedAddr}
+
+	var someID NodeID
+	someID[0] = 7
+	if !call.matches(someID, seedAddr) {
+		t.Fatalf("a reply from the seed's address should match when the target ID was unknown")
+	}
+	if call.matches(someID, &net.UDPAddr{IP: net.ParseIP("10.0.0.99"), Port: 9000}) {
+		t.Fatalf("a reply from a different address must still be rejected when the target ID was unknown")
+	}
+}
+
+func TestRecordEncodeDecodeRoundTrip(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	want := Record{IP: net.ParseIP("192.168.1.5").To4(), WebDAVPort: 8080, PublicKey: pub}
+
+	b, err := encodeRecord(want)
+	if err != nil {
+		t.Fatalf("encodeRecord: %v", err)
+	}
+	got, err := decodeRecord(b)
+	if err != nil {
+		t.Fatalf("decodeRecord: %v", err)
+	}
+	if got.WebDAVPort != want.WebDAVPort || !got.IP.Equal(want.IP) {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", got, want)
+	}
+}
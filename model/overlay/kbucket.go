@@ -0,0 +1,160 @@
+package overlay
+
+import "sync"
+
+// bucketSize 是每个 k-bucket 最多保存的联系人数（Kademlia 论文中的 k）。
+const bucketSize = 20
+
+// kBucket 保存与 self 的 XOR 距离落在同一前缀长度区间内的联系人，
+// 按最近使用顺序排列（最久未联系的在前，与标准 Kademlia LRU 驱逐策略一致）。
+type kBucket struct {
+	mu       sync.Mutex
+	contacts []Contact
+}
+
+// touch 把联系人标记为"最近联系"：已存在则移到队尾，不存在则在未满时追加。
+// 返回 true 表示联系人已在桶中或被成功加入；false 表示桶已满且为新联系人
+// （调用方应当 ping 队首节点以决定是否驱逐）。
+func (b *kBucket) touch(c Contact) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, existing := range b.contacts {
+		if existing.ID == c.ID {
+			b.contacts = append(b.contacts[:i], b.contacts[i+1:]...)
+			b.contacts = append(b.contacts, c)
+			return true
+		}
+	}
+	if len(b.contacts) < bucketSize {
+		b.contacts = append(b.contacts, c)
+		return true
+	}
+	return false
+}
+
+// oldest 返回桶中最久未联系的联系人（用于判断是否还活着、决定是否驱逐）。
+func (b *kBucket) oldest() (Contact, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.contacts) == 0 {
+		return Contact{}, false
+	}
+	return b.contacts[0], true
+}
+
+// evictOldest 移除桶中最久未联系的联系人，为新联系人腾出位置。
+func (b *kBucket) evictOldest() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.contacts) > 0 {
+		b.contacts = b.contacts[1:]
+	}
+}
+
+// snapshot 返回桶内联系人的拷贝。
+func (b *kBucket) snapshot() []Contact {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]Contact, len(b.contacts))
+	copy(out, b.contacts)
+	return out
+}
+
+func (b *kBucket) remove(id NodeID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, c := range b.contacts {
+		if c.ID == id {
+			b.contacts = append(b.contacts[:i], b.contacts[i+1:]...)
+			return
+		}
+	}
+}
+
+// RoutingTable 按 XOR 距离把联系人组织进 idBits 个 k-bucket。
+type RoutingTable struct {
+	self    NodeID
+	buckets [idBits]*kBucket
+}
+
+// NewRoutingTable 创建一个以 self 为中心的空路由表。
+func NewRoutingTable(self NodeID) *RoutingTable {
+	rt := &RoutingTable{self: self}
+	for i := range rt.buckets {
+		rt.buckets[i] = &kBucket{}
+	}
+	return rt
+}
+
+// bucketFor 返回某个 ID 应当落入的 bucket 下标：与 self 的 XOR 距离的前导零比特数。
+func (rt *RoutingTable) bucketFor(id NodeID) int {
+	idx := rt.self.Xor(id).PrefixLen()
+	if idx >= idBits {
+		idx = idBits - 1
+	}
+	return idx
+}
+
+// Add 记录一次与 contact 的联系。若对应 bucket 已满，调用方应当先 ping 队首
+// 节点，存活则保留、否则用 Replace 驱逐后重试。
+func (rt *RoutingTable) Add(c Contact) bool {
+	if c.ID == rt.self {
+		return false
+	}
+	return rt.buckets[rt.bucketFor(c.ID)].touch(c)
+}
+
+// Oldest 返回某个联系人所在 bucket 中最久未联系的节点。
+func (rt *RoutingTable) Oldest(id NodeID) (Contact, bool) {
+	return rt.buckets[rt.bucketFor(id)].oldest()
+}
+
+// Replace 驱逐某个 bucket 里最久未联系的节点，为 newContact 腾出位置。
+func (rt *RoutingTable) Replace(newContact Contact) {
+	b := rt.buckets[rt.bucketFor(newContact.ID)]
+	b.evictOldest()
+	b.touch(newContact)
+}
+
+// Remove 从路由表中彻底移除某个节点（例如确认其已不可达）。
+func (rt *RoutingTable) Remove(id NodeID) {
+	rt.buckets[rt.bucketFor(id)].remove(id)
+}
+
+// Closest 返回路由表中已知的、与 target 按 XOR 距离最近的至多 n 个联系人。
+func (rt *RoutingTable) Closest(target NodeID, n int) []Contact {
+	var all []Contact
+	for _, b := range rt.buckets {
+		all = append(all, b.snapshot()...)
+	}
+	sortByDistance(all, target)
+	if len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
+
+// BucketIndexes 返回所有非空 bucket 的下标，供周期性刷新使用。
+func (rt *RoutingTable) BucketIndexes() []int {
+	var idxs []int
+	for i, b := range rt.buckets {
+		if len(b.snapshot()) > 0 {
+			idxs = append(idxs, i)
+		}
+	}
+	return idxs
+}
+
+func sortByDistance(contacts []Contact, target NodeID) {
+	for i := 1; i < len(contacts); i++ {
+		for j := i; j > 0; j-- {
+			di := contacts[j].ID.Xor(target)
+			dj := contacts[j-1].ID.Xor(target)
+			if di.Less(dj) {
+				contacts[j], contacts[j-1] = contacts[j-1], contacts[j]
+			} else {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package overlay
+
+import "testing"
+
+func contactWithID(b byte) Contact {
+	var id NodeID
+	id[len(id)-1] = b
+	return Contact{ID: id}
+}
+
+func TestKBucketTouchMovesToBack(t *testing.T) {
+	kb := &kBucket{}
+	kb.touch(contactWithID(1))
+	kb.touch(contactWithID(2))
+	kb.touch(contactWithID(1)) // re-touch: should move to the back, not duplicate
+
+	got := kb.snapshot()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 contacts after re-touch, got %d", len(got))
+	}
+	if got[len(got)-1].ID != contactWithID(1).ID {
+		t.Fatalf("re-touched contact should be most-recently-used (at the back)")
+	}
+}
+
+func TestKBucketEvictOldestRemovesFront(t *testing.T) {
+	kb := &kBucket{}
+	kb.touch(contactWithID(1))
+	kb.touch(contactWithID(2))
+
+	oldest, ok := kb.oldest()
+	if !ok || oldest.ID != contactWithID(1).ID {
+		t.Fatalf("expected contact 1 to be oldest")
+	}
+
+	kb.evictOldest()
+	got := kb.snapshot()
+	if len(got) != 1 || got[0].ID != contactWithID(2).ID {
+		t.Fatalf("expected only contact 2 to remain after eviction, got %v", got)
+	}
+}
+
+func TestKBucketFullRejectsNewContact(t *testing.T) {
+	kb := &kBucket{}
+	for i := 0; i < bucketSize; i++ {
+		if !kb.touch(contactWithID(byte(i))) {
+			t.Fatalf("bucket should accept contacts up to bucketSize")
+		}
+	}
+	if kb.touch(contactWithID(255)) {
+		t.Fatalf("full bucket should reject a brand new contact")
+	}
+}
@@ -0,0 +1,141 @@
+// Package overlay 实现了一个小型的 Kademlia 风格结构化覆盖网络，
+// 让 LANShare 节点能够跨越单个二层网段（例如跨 VLAN，或经由汇聚引导节点）
+// 互相发现彼此，而不仅仅依赖同一局域网内的 BLE/mDNS 发现。
+package overlay
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+)
+
+// idBits 是节点 ID 的位宽，等于 SHA-1 摘要长度。
+const idBits = sha1.Size * 8
+
+// NodeID 是节点在覆盖网络中的 160-bit 标识，取自其 Ed25519 公钥的 SHA-1 摘要。
+type NodeID [sha1.Size]byte
+
+// NewNodeID 由 Ed25519 公钥派生出节点 ID。
+func NewNodeID(pub ed25519.PublicKey) NodeID {
+	return NodeID(sha1.Sum(pub))
+}
+
+// String 返回十六进制表示，便于日志与 TXT/调试输出。
+func (id NodeID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// ParseNodeID 把 NodeID.String() 的输出解析回 NodeID。
+func ParseNodeID(s string) (NodeID, error) {
+	var id NodeID
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return id, fmt.Errorf("parse node id %q: %w", s, err)
+	}
+	if len(b) != len(id) {
+		return id, fmt.Errorf("parse node id %q: expected %d bytes, got %d", s, len(id), len(b))
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+// Xor 返回两个 ID 的按位异或，即 Kademlia 距离度量。
+func (id NodeID) Xor(other NodeID) NodeID {
+	var out NodeID
+	for i := range id {
+		out[i] = id[i] ^ other[i]
+	}
+	return out
+}
+
+// PrefixLen 返回该 ID 前导零比特的个数，即该 ID 落在哪一个 k-bucket（与 self 的
+// 距离 2^(idBits-1-PrefixLen) 到 2^(idBits-PrefixLen) 之间）。
+func (id NodeID) PrefixLen() int {
+	for i, b := range id {
+		if b == 0 {
+			continue
+		}
+		for bit := 0; bit < 8; bit++ {
+			if b&(0x80>>uint(bit)) != 0 {
+				return i*8 + bit
+			}
+		}
+	}
+	return idBits
+}
+
+// RandomIDInBucket 生成一个随机 ID，它与 self 的 XOR 距离恰好落在下标为
+// bucketIdx 的 k-bucket 里（即前 bucketIdx 位与 self 相同，第 bucketIdx 位
+// 相反，其后各位随机），用于 bucket 刷新时选取目标而不是重复查询 self。
+func RandomIDInBucket(self NodeID, bucketIdx int) NodeID {
+	if bucketIdx < 0 {
+		bucketIdx = 0
+	}
+	if bucketIdx >= idBits {
+		bucketIdx = idBits - 1
+	}
+
+	var id NodeID
+	copy(id[:], self[:])
+
+	byteIdx := bucketIdx / 8
+	bitIdx := uint(bucketIdx % 8)
+
+	// 翻转第 bucketIdx 位，使其前缀长度恰好等于 bucketIdx。
+	id[byteIdx] ^= 0x80 >> bitIdx
+
+	// 随机化该位之后的所有比特，保持前缀不变。
+	tail := make([]byte, len(id)-byteIdx)
+	_, _ = rand.Read(tail)
+	lowerMask := byte(0xFF) >> (bitIdx + 1)
+	id[byteIdx] = (id[byteIdx] &^ lowerMask) | (tail[0] & lowerMask)
+	copy(id[byteIdx+1:], tail[1:])
+
+	return id
+}
+
+// Less 按无符号大小比较两个 ID，XOR 距离排序时用它挑选最近节点。
+func (id NodeID) Less(other NodeID) bool {
+	for i := range id {
+		if id[i] != other[i] {
+			return id[i] < other[i]
+		}
+	}
+	return false
+}
+
+// Contact 是路由表与 FIND_NODE/FIND_VALUE 回复中携带的节点描述。
+type Contact struct {
+	ID         NodeID            `json:"id"`
+	IP         net.IP            `json:"ip"`
+	Port       int               `json:"port"` // DHT UDP 端口
+	WebDAVPort int               `json:"webdavPort"`
+	PublicKey  ed25519.PublicKey `json:"publicKey"`
+}
+
+// Addr 返回该节点 DHT 端口对应的 UDP 地址。
+func (c Contact) Addr() string {
+	return fmt.Sprintf("%s:%d", c.IP, c.Port)
+}
+
+// Record 是 DHT 中针对某个 nodeID 存储的值：{ip, webdavPort, publicKey}，
+// 供 LookupPeer 解析出可直接拨号的地址。
+type Record struct {
+	IP         net.IP            `json:"ip"`
+	WebDAVPort int               `json:"webdavPort"`
+	PublicKey  ed25519.PublicKey `json:"publicKey"`
+}
+
+func encodeRecord(r Record) ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func decodeRecord(b []byte) (Record, error) {
+	var r Record
+	err := json.Unmarshal(b, &r)
+	return r, err
+}
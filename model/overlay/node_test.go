@@ -0,0 +1,44 @@
+package overlay
+
+import (
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestNodeIDXorPrefixLen(t *testing.T) {
+	var a, b NodeID
+	a[0] = 0xff
+	b[0] = 0xff
+	if got := a.Xor(b).PrefixLen(); got != idBits {
+		t.Fatalf("identical IDs should have max prefix length, got %d", got)
+	}
+
+	b[0] = 0x7f // differs at the top bit
+	if got := a.Xor(b).PrefixLen(); got != 0 {
+		t.Fatalf("expected prefix length 0 for top-bit difference, got %d", got)
+	}
+}
+
+func TestRandomIDInBucketMatchesBucketIndex(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	self := NewNodeID(priv.Public().(ed25519.PublicKey))
+
+	for _, idx := range []int{0, 1, 7, 8, 63, idBits - 1} {
+		id := RandomIDInBucket(self, idx)
+		if got := self.Xor(id).PrefixLen(); got != idx {
+			t.Fatalf("bucket %d: RandomIDInBucket produced prefix length %d", idx, got)
+		}
+	}
+}
+
+func TestRandomIDInBucketIsRandomized(t *testing.T) {
+	var self NodeID
+	a := RandomIDInBucket(self, 0)
+	b := RandomIDInBucket(self, 0)
+	if a == b {
+		t.Fatalf("expected two calls to RandomIDInBucket to differ in their random tail")
+	}
+}
@@ -0,0 +1,99 @@
+package overlay
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// rpcType 标识 Kademlia 的四种 RPC 及其回复。
+type rpcType string
+
+const (
+	rpcPing      rpcType = "PING"
+	rpcPong      rpcType = "PONG"
+	rpcStore     rpcType = "STORE"
+	rpcStoreAck  rpcType = "STORE_ACK"
+	rpcFindNode  rpcType = "FIND_NODE"
+	rpcNodes     rpcType = "NODES"
+	rpcFindValue rpcType = "FIND_VALUE"
+	rpcValue     rpcType = "VALUE"
+)
+
+// requestID 唯一标识一次 RPC 往返，用于把回复与挂起的请求匹配起来。
+type requestID [8]byte
+
+// message 是覆盖网络 UDP 报文的信封：除 Signature 外的所有字段都会被签名，
+// Nonce 必须严格递增以防重放。
+type message struct {
+	Type      rpcType   `json:"type"`
+	RequestID requestID `json:"requestId"`
+	From      Contact   `json:"from"`
+	Nonce     uint64    `json:"nonce"`
+
+	// FIND_NODE / FIND_VALUE 请求
+	Target *NodeID `json:"target,omitempty"`
+
+	// STORE / FIND_VALUE 回复（VALUE）
+	Key   *NodeID `json:"key,omitempty"`
+	Value []byte  `json:"value,omitempty"`
+	TTLMs int64   `json:"ttlMs,omitempty"`
+
+	// FIND_NODE / FIND_VALUE(未命中) 回复
+	Closest []Contact `json:"closest,omitempty"`
+
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// signingBytes 返回报文中参与签名的确定性字节表示（签名字段本身除外）。
+func (m message) signingBytes() ([]byte, error) {
+	cp := m
+	cp.Signature = nil
+	return json.Marshal(cp)
+}
+
+// sign 使用节点的 Ed25519 私钥对报文签名。
+func (m *message) sign(priv ed25519.PrivateKey) error {
+	b, err := m.signingBytes()
+	if err != nil {
+		return err
+	}
+	m.Signature = ed25519.Sign(priv, b)
+	return nil
+}
+
+// verify 校验报文签名是否匹配 From.PublicKey。
+func (m message) verify() error {
+	if len(m.From.PublicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("message from %s: missing/invalid public key", m.From.ID)
+	}
+	if NewNodeID(m.From.PublicKey) != m.From.ID {
+		return fmt.Errorf("message from %s: public key does not match claimed node ID", m.From.ID)
+	}
+	b, err := m.signingBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(m.From.PublicKey, b, m.Signature) {
+		return fmt.Errorf("message from %s: signature verification failed", m.From.ID)
+	}
+	return nil
+}
+
+func encodeMessage(m message) ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func decodeMessage(b []byte) (message, error) {
+	var m message
+	err := json.Unmarshal(b, &m)
+	return m, err
+}
+
+// newRequestID 从单调递增的计数器派生一个请求 ID，足以在本节点当前会话内保持唯一。
+func newRequestID(counter uint64) requestID {
+	var id requestID
+	binary.BigEndian.PutUint64(id[:], counter)
+	return id
+}
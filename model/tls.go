@@ -0,0 +1,91 @@
+package model
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// ensureSelfSignedCert 在 certFile/keyFile 不存在时生成一张自签名证书，
+// 覆盖 localhost、127.0.0.1 以及传入的局域网 IP，有效期一年。返回证书的
+// SHA-256 指纹，供调用方把它钉进 BLE/mDNS 广播以实现"首次连接即信任"（TOFU）。
+func ensureSelfSignedCert(certFile, keyFile string, lanIP net.IP) ([32]byte, error) {
+	if _, err := os.Stat(certFile); err == nil {
+		if _, err := os.Stat(keyFile); err == nil {
+			return certFingerprint(certFile)
+		}
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("generate key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("generate serial: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "lanshare-node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+	}
+	if lanIP != nil {
+		tmpl.IPAddresses = append(tmpl.IPAddresses, lanIP)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("create certificate: %w", err)
+	}
+
+	if err := writePEM(certFile, "CERTIFICATE", der); err != nil {
+		return [32]byte{}, err
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("marshal key: %w", err)
+	}
+	if err := writePEM(keyFile, "EC PRIVATE KEY", keyBytes); err != nil {
+		return [32]byte{}, err
+	}
+
+	return sha256.Sum256(der), nil
+}
+
+func writePEM(path, blockType string, der []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+	return pem.Encode(f, &pem.Block{Type: blockType, Bytes: der})
+}
+
+// certFingerprint 返回磁盘上已有证书的 SHA-256 指纹。
+func certFingerprint(certFile string) ([32]byte, error) {
+	b, err := os.ReadFile(certFile)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("read %s: %w", certFile, err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return [32]byte{}, fmt.Errorf("%s: not a valid PEM certificate", certFile)
+	}
+	return sha256.Sum256(block.Bytes), nil
+}
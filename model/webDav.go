@@ -2,6 +2,9 @@ package model
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"log"
 	"net"
@@ -11,17 +14,35 @@ import (
 	"sync"
 	"time"
 
+	"LANShare/model/overlay"
+
 	"golang.org/x/net/webdav"
 )
 
+// webdavServiceVersion 是通过 mDNS/Bonjour 公告时写进 TXT "version=" 条目的
+// 展示用版本号。
+const webdavServiceVersion = "0.1.0"
+
 // WebDAVService 提供一个简单的本地 WebDAV 服务器，可以在局域网中被访问。
 // 它会将给定目录作为根目录暴露出来，并提供 Start/Stop 方法供 Wails 调用。
 type WebDAVService struct {
-	mu       sync.Mutex
-	srv      *http.Server
-	listener net.Listener
-	root     string
-	running  bool
+	mu           sync.Mutex
+	srv          *http.Server
+	listener     net.Listener
+	root         string
+	running      bool
+	advertiser   *Advertiser
+	overlayPriv  ed25519.PrivateKey
+	overlayDHT   *overlay.DHT
+	overlaySeeds []string
+	overlayStop  context.CancelFunc
+	discovery    *Discovery
+
+	tokens            TokenStore
+	basicUser         string
+	basicPass         string
+	tlsFingerprint    [32]byte
+	hasTLSFingerprint bool
 }
 
 // NewWebDAVService 创建一个新的 WebDAVService。root 如果为空，会使用当前工作目录的 "shared" 子目录。
@@ -38,7 +59,61 @@ func NewWebDAVService(root string) *WebDAVService {
 		log.Printf("failed to create webdav root: %v", err)
 	}
 
-	return &WebDAVService{root: root}
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Printf("failed to generate overlay identity key: %v", err)
+	}
+
+	return &WebDAVService{root: root, overlayPriv: priv}
+}
+
+// SetOverlaySeeds 配置跨网段发现用的 Kademlia 引导节点地址（"host:port" 形式）。
+// 必须在 Start 之前调用才会生效。
+func (w *WebDAVService) SetOverlaySeeds(seeds []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.overlaySeeds = seeds
+}
+
+// SetBasicAuth 配置一组全局 HTTP Basic 凭证，适合单一受信任用户的场景。
+// 留空 user 则关闭 Basic 认证，只接受 Bearer 令牌。
+func (w *WebDAVService) SetBasicAuth(user, pass string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.basicUser = user
+	w.basicPass = pass
+}
+
+// SetTokenStore 替换默认的内存令牌存储，例如传入 NewFileTokenStore 的结果
+// 以便令牌在进程重启后继续有效。必须在 Start 之前调用才会生效。
+func (w *WebDAVService) SetTokenStore(store TokenStore) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tokens = store
+}
+
+func (w *WebDAVService) tokenStore() TokenStore {
+	if w.tokens == nil {
+		w.tokens = NewMemTokenStore()
+	}
+	return w.tokens
+}
+
+// IssueToken 颁发一个限定作用域（只读/读写）与有效期的令牌，供某个对端用
+// Bearer 认证访问 WebDAV。
+func (w *WebDAVService) IssueToken(peerID string, scope TokenScope, ttl time.Duration) (Token, error) {
+	w.mu.Lock()
+	store := w.tokenStore()
+	w.mu.Unlock()
+	return store.Issue(peerID, scope, "", ttl)
+}
+
+// RevokeToken 撤销之前颁发的一个令牌。
+func (w *WebDAVService) RevokeToken(id string) error {
+	w.mu.Lock()
+	store := w.tokenStore()
+	w.mu.Unlock()
+	return store.Revoke(id)
 }
 
 // Start 启动 WebDAV 服务，监听在指定端口（port 0 表示自动分配）。
@@ -57,7 +132,7 @@ func (w *WebDAVService) Start(ctx context.Context, port int) (string, error) {
 	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/", handler)
+	mux.Handle("/", authMiddleware(handler, w.tokenStore(), w.basicUser, w.basicPass))
 
 	srv := &http.Server{
 		Handler: mux,
@@ -94,13 +169,230 @@ func (w *WebDAVService) Start(ctx context.Context, port int) (string, error) {
 	}
 	actualPort := ln.Addr().(*net.TCPAddr).Port
 
+	if w.overlayPriv != nil {
+		overlayCtx, overlayCancel := context.WithCancel(context.Background())
+		dht := overlay.NewDHT(w.overlayPriv, net.ParseIP(host), 0, actualPort)
+		if err := dht.ListenAndServe(overlayCtx); err != nil {
+			log.Printf("overlay: failed to start: %v", err)
+			overlayCancel()
+		} else {
+			w.overlayDHT = dht
+			w.overlayStop = overlayCancel
+			seeds := w.overlaySeeds
+			go func() {
+				if err := dht.Bootstrap(overlayCtx, seeds); err != nil {
+					log.Printf("overlay: bootstrap incomplete: %v", err)
+				}
+			}()
+		}
+	}
+
+	nodeID := ""
+	if w.overlayPriv != nil {
+		nodeID = overlay.NewNodeID(w.overlayPriv.Public().(ed25519.PublicKey)).String()
+	}
+	disc := NewDiscovery(nodeID, webdavServiceVersion)
+	if w.hasTLSFingerprint {
+		disc.SetTLSFingerprint(fmt.Sprintf("%x", w.tlsFingerprint))
+	}
+	if err := disc.Start(context.Background(), actualPort, w.root); err != nil {
+		log.Printf("discovery: failed to start: %v", err)
+	} else {
+		w.discovery = disc
+	}
+
 	return fmt.Sprintf("%s:%d", host, actualPort), nil
 }
 
+// StartTLS 与 Start 一样以带认证的 handler 提供 WebDAV 服务，但使用 TLS 监听。
+// certFile/keyFile 不存在时会自动生成一张自签名证书并写入这两个路径；证书的
+// SHA-256 指纹被记录下来，随后可以通过 TLSFingerprint 取出。与 Start 不同，
+// StartTLS 不启动 overlay 覆盖网络和 mDNS 发现，也不发起 BLE 广播；记录下的
+// 指纹只会在之后调用 Start/StartWithAdvertise 时写进 mDNS TXT 记录与 BLE 广播。
+func (w *WebDAVService) StartTLS(ctx context.Context, port int, certFile, keyFile string) (string, error) {
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		return "", fmt.Errorf("webdav already running")
+	}
+
+	fingerprint, err := ensureSelfSignedCert(certFile, keyFile, net.ParseIP(localIPv4()))
+	if err != nil {
+		w.mu.Unlock()
+		return "", fmt.Errorf("prepare TLS certificate: %w", err)
+	}
+	w.tlsFingerprint = fingerprint
+	w.hasTLSFingerprint = true
+
+	handler := &webdav.Handler{
+		Prefix:     "/",
+		FileSystem: webdav.Dir(w.root),
+		LockSystem: webdav.NewMemLS(),
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", authMiddleware(handler, w.tokenStore(), w.basicUser, w.basicPass))
+
+	srv := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	addr := fmt.Sprintf("0.0.0.0:%d", port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		w.mu.Unlock()
+		return "", err
+	}
+
+	w.srv = srv
+	w.listener = ln
+	w.running = true
+	w.mu.Unlock()
+
+	go func() {
+		if err := srv.ServeTLS(ln, certFile, keyFile); err != nil && err != http.ErrServerClosed {
+			log.Printf("webdav TLS server error: %v", err)
+		}
+		w.mu.Lock()
+		w.running = false
+		w.mu.Unlock()
+	}()
+
+	host := localIPv4()
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	actualPort := ln.Addr().(*net.TCPAddr).Port
+
+	return fmt.Sprintf("%s:%d", host, actualPort), nil
+}
+
+// TLSFingerprint 返回当前通过 StartTLS 启动的服务所使用证书的 SHA-256 指纹，
+// 以及该指纹是否可用（即是否已经以 TLS 方式启动过）。
+func (w *WebDAVService) TLSFingerprint() ([32]byte, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.tlsFingerprint, w.hasTLSFingerprint
+}
+
+// LookupPeer 在跨子网覆盖网络中查找某个节点 ID 对应的地址记录。
+func (w *WebDAVService) LookupPeer(ctx context.Context, nodeID string) (overlay.Record, bool, error) {
+	w.mu.Lock()
+	dht := w.overlayDHT
+	w.mu.Unlock()
+	if dht == nil {
+		return overlay.Record{}, false, fmt.Errorf("overlay not started")
+	}
+	id, err := overlay.ParseNodeID(nodeID)
+	if err != nil {
+		return overlay.Record{}, false, err
+	}
+	return dht.LookupPeer(ctx, id)
+}
+
+// NearestPeers 返回覆盖网络路由表中已知的、离本节点最近的 n 个联系人。
+func (w *WebDAVService) NearestPeers(n int) ([]overlay.Contact, error) {
+	w.mu.Lock()
+	dht := w.overlayDHT
+	w.mu.Unlock()
+	if dht == nil {
+		return nil, fmt.Errorf("overlay not started")
+	}
+	return dht.NearestPeers(n), nil
+}
+
+// Peers 返回当前通过 mDNS/Bonjour 发现的局域网内其他 LANShare/WebDAV 节点。
+func (w *WebDAVService) Peers() []Peer {
+	w.mu.Lock()
+	disc := w.discovery
+	w.mu.Unlock()
+	if disc == nil {
+		return nil
+	}
+	return disc.Peers()
+}
+
+// PeersChanged 返回一个通道，每当 Peers() 的结果发生变化时收到一个通知；
+// Discovery 尚未启动时返回 nil。
+func (w *WebDAVService) PeersChanged() <-chan struct{} {
+	w.mu.Lock()
+	disc := w.discovery
+	w.mu.Unlock()
+	if disc == nil {
+		return nil
+	}
+	return disc.Changed()
+}
+
+// StartWithAdvertise 启动 WebDAV 服务的同时，通过 BLE 把它广播给附近扫描中的设备：
+// 一条带 manufacturer-data 的广播让对方无需连接即可判断这是不是 LANShare 节点、
+// 服务集合是否变化，另外注册一个 GATT 服务暴露共享根路径、当前 IP 和配对 nonce，
+// 供扫描到广播后决定连接的设备进一步核实。
+func (w *WebDAVService) StartWithAdvertise(ctx context.Context, port int) (string, error) {
+	addr, err := w.Start(ctx, port)
+	if err != nil {
+		return "", err
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, fmt.Errorf("parse listen addr: %w", err)
+	}
+	actualPort := 0
+	_, _ = fmt.Sscanf(portStr, "%d", &actualPort)
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return addr, fmt.Errorf("generate pairing nonce: %w", err)
+	}
+
+	w.mu.Lock()
+	w.advertiser = NewAdvertiser()
+	adv := w.advertiser
+	w.mu.Unlock()
+
+	var tlsFingerprint []byte
+	if fp, ok := w.TLSFingerprint(); ok {
+		tlsFingerprint = fp[:]
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+	}
+	if err := adv.Start(ctx, hostname, w.root, net.ParseIP(host), actualPort, w.rootFingerprint(), nonce, tlsFingerprint); err != nil {
+		return addr, fmt.Errorf("start advertiser: %w", err)
+	}
+
+	advertisementCache.StartJanitor(ctx, lanshareAdvTTL)
+	return addr, nil
+}
+
+// rootFingerprint 返回共享根目录的一个稳定短哈希，作为广播 payload 的一部分，
+// 这样扫描方可以在不连接的情况下判断远端服务集合自上次见到后是否发生变化。
+func (w *WebDAVService) rootFingerprint() string {
+	sum := sha256.Sum256([]byte(w.root))
+	return fmt.Sprintf("%x", sum[:8])
+}
+
 // Stop 优雅关闭 WebDAV 服务。
 func (w *WebDAVService) Stop(ctx context.Context) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	if w.advertiser != nil {
+		w.advertiser.Stop()
+		w.advertiser = nil
+	}
+	if w.discovery != nil {
+		w.discovery.Stop()
+		w.discovery = nil
+	}
+	if w.overlayStop != nil {
+		w.overlayStop()
+		w.overlayStop = nil
+		w.overlayDHT = nil
+	}
 	if !w.running {
 		return nil
 	}